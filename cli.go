@@ -0,0 +1,827 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gitFetchHelper/commands"
+	"gitFetchHelper/config"
+)
+
+// name of this program as shown in help text.
+const programName = "gitFetchHelper"
+
+// a sub command of the CLI. ie "fetch", "cloneYoloRepos".
+type command struct {
+	// long descriptive name typed on the command line.
+	name string
+	// old or short names that still work. ie "init3" for cloneYoloRepos.
+	aliases []commandAlias
+	// flags and args shown after the name in the usage line. ie "[--sym name | --all-remotes]"
+	usage string
+	// 1 line description shown in the command list.
+	summary string
+	// longer description shown by "help <command>". optional.
+	help string
+	// range of positional (non flag) args accepted. maxArgs < 0 means unlimited.
+	minArgs, maxArgs int
+	// what the positional args are. used by shell completion.
+	args argKind
+	// fixed values for the positional args. used by shell completion when args is argChoice.
+	choices []string
+	// what the values of non-bool flags are, by flag name. used by shell completion.
+	flagArgs map[string]argKind
+	// fixed values of the flags that are argChoice in flagArgs. used by shell completion.
+	flagChoices map[string][]string
+	// true if the command doesn't need repos.jsonc loaded. ie help.
+	skipInit bool
+	// register the command's flags on fs. returns the func to run after flags are parsed.
+	// s is the loaded config, nil if skipInit. args are the positional (non flag) arguments.
+	setup func(fs *flag.FlagSet) func(ctx context.Context, s *session, args []string) error
+}
+
+// kind of a positional arg or flag value. used by shell completion.
+type argKind int
+
+const (
+	argNone argKind = iota
+	argCommand
+	argRepoName
+	argSym
+	argDir
+	argFile
+	argChoice
+)
+
+// an old command name kept for muscle memory. It may preset some args,
+// ie "init3Shallow" is "cloneYoloRepos --mode shallow".
+type commandAlias struct {
+	name string
+	args []string
+}
+
+// the commands of the CLI, in the order shown by help.
+func cliCommands() []*command {
+	cmds := make([]*command, 0, 32)
+
+	// fetchUpstream, fetchDefault, fetchMine, diffUpstream, diffDefault, diffMine
+	for _, rc := range remoteCommands() {
+		rc := rc
+		cmd := &command{
+			name:    rc.name,
+			summary: remoteCommandSummary(rc),
+			setup: func(_ *flag.FlagSet) func(context.Context, *session, []string) error {
+				return func(ctx context.Context, s *session, _ []string) error {
+					rc.run(ctx, s, rc.remoteType, "")
+					return nil
+				}
+			},
+		}
+		if rc.op == "fetch" {
+			cmd.usage = "[--skip-unchanged]"
+			cmd.setup = func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				skipUnchanged := addSkipUnchangedFlag(fs)
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.Fetch(ctx, s.env, s.repos, commands.FetchOptions{Remote: rc.remoteType, SkipUnchanged: *skipUnchanged}))
+					return nil
+				}
+			}
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	cmds = append(cmds,
+		&command{
+			name:     "fetch",
+			usage:    "[--sym name | --all-remotes] [--skip-unchanged]",
+			summary:  "fetch any remote by Sym, or all remotes",
+			flagArgs: map[string]argKind{"sym": argSym},
+			help: `Fetch the remote with Sym "name" for each repo. Repos without the Sym are
+skipped quietly rather than reported as failures. With --all-remotes every
+configured remote is fetched. With no flags the default remote is fetched.
+With --skip-unchanged the remote's branch (BranchMain on the upstream, BranchUse
+on mine) is checked with git ls-remote first and the fetch is skipped when it
+matches the local tracking branch.
+Much faster when most repos have nothing new.`,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				sel := addRemoteFlags(fs)
+				skipUnchanged := addSkipUnchangedFlag(fs)
+				return func(ctx context.Context, s *session, _ []string) error {
+					remoteType, sym, err := sel()
+					if err != nil {
+						return err
+					}
+					s.printReport(commands.Fetch(ctx, s.env, s.repos, commands.FetchOptions{Remote: remoteType, Sym: sym, SkipUnchanged: *skipUnchanged}))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:     "diff",
+			usage:    "[--sym name | --all-remotes]",
+			summary:  "list repos with new code in any remote by Sym, or all remotes",
+			flagArgs: map[string]argKind{"sym": argSym},
+			help: `Diff against the remote with Sym "name" for each repo. Repos without the Sym
+are skipped quietly rather than reported as failures. With --all-remotes every
+configured remote is diffed. With no flags the default remote is diffed.`,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				sel := addRemoteFlags(fs)
+				return func(ctx context.Context, s *session, _ []string) error {
+					remoteType, sym, err := sel()
+					if err != nil {
+						return err
+					}
+					s.printReport(commands.Diff(ctx, s.env, s.repos, remoteType, sym))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "mergeMine",
+			summary: `merge the "mine" remote into BranchUse`,
+			help: `Merge the "mine" remote's BranchUse into the local BranchUse. The "mine"
+remotes are my forks or personal projects so it's OK to merge them without
+review. BranchUse must already be checked out. The Sym of the remote merged is
+the mergeSym setting.`,
+			setup: func(_ *flag.FlagSet) func(context.Context, *session, []string) error {
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.MergeMine(ctx, s.env, s.repos))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "pushMine",
+			usage:   "[--force-with-lease]",
+			summary: `push BranchMain and BranchUse to the "mine" remote`,
+			help: `Push BranchMain and BranchUse to the "mine" remote of each repo.
+Non-fast-forward pushes are rejected unless --force-with-lease is given.`,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				forceWithLease := fs.Bool("force-with-lease", false,
+					"allow non-fast-forward pushes if the remote branch is where we last saw it")
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.PushMine(ctx, s.env, s.repos, *forceWithLease))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "setRemotes",
+			aliases: []commandAlias{{name: "init"}, {name: "setUpstreamRemotes"}},
+			usage:   "[--fetch]",
+			summary: "add the configured remotes to repos missing them",
+			help: `Add every remote configured in repos.jsonc (alias and URL) to each repo where
+it is missing. Reports each remote created, and remotes whose URL doesn't match
+repos.jsonc (use audit --fix to change those). With --fetch newly added remotes
+are fetched right away. Useful after a fresh emacs config clone to a new
+computer.`,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				fetch := fs.Bool("fetch", false, "fetch each newly added remote")
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.SetRemotes(ctx, s.env, s.repos, *fetch))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "switchToBranches",
+			aliases: []commandAlias{{name: "init2"}},
+			summary: "checkout BranchUse and reset it to the default remote",
+			help: `Checkout BranchUse in each repo, then hard reset it to the default remote's
+version of the branch. Useful after a fresh emacs config clone to a new
+computer to avoid detached head state.`,
+			setup: func(_ *flag.FlagSet) func(context.Context, *session, []string) error {
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.SwitchToBranches(ctx, s.env, s.repos))
+					return nil
+				}
+			},
+		},
+		&command{
+			name: "cloneYoloRepos",
+			aliases: []commandAlias{
+				{name: "init3"},
+				{name: "init3Shallow", args: []string{"--mode", "shallow"}},
+			},
+			usage:       "[--mode full|shallow|blobless]",
+			flagArgs:    map[string]argKind{"mode": argChoice},
+			flagChoices: map[string][]string{"mode": choiceNames(config.CloneModes)},
+			summary:     "clone yolo repos that are not on disk yet",
+			help: `Clone each "yolo" repo (not a git submodule) that does not exist on disk yet.
+Each repo is cloned by its "cloneMode" in repos.jsonc, --mode overrides it for
+every repo. Full clones are the default as shallow clones mess up later
+merges/rebases. Blobless clones (--filter=blob:none) keep the full history for
+merges but only download file contents when checked out.`,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				mode := fs.String("mode", "", "clone mode for every repo: full, shallow or blobless. default each repo's cloneMode")
+				return func(ctx context.Context, s *session, _ []string) error {
+					var modeOverride config.CloneMode
+					if *mode != "" {
+						var err error
+						if modeOverride, err = config.ParseCloneMode(*mode); err != nil {
+							return err
+						}
+					}
+					s.printReport(commands.CloneYolo(ctx, s.env, s.repos, modeOverride))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "unshallow",
+			summary: "fetch the full history of shallow cloned yolo repos",
+			help: `Run git fetch --unshallow on the default remote of each yolo repo that is a
+shallow clone. Full and blobless clones are left alone.`,
+			setup: func(_ *flag.FlagSet) func(context.Context, *session, []string) error {
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.Unshallow(ctx, s.env, s.repos))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "verify",
+			usage:   "[--reclone]",
+			summary: "check the existing yolo folders are healthy clones",
+			help: `Check each yolo repo folder that exists on disk: it must be the root of a git
+work tree (not a plain folder nested in another repo), not a git submodule,
+have the default remote with the configured URL, and have BranchUse as a local
+or remote tracking branch. With --reclone each broken folder is moved to
+<yoloRoot>Archive as <name>.broken-<time> and cloned again. Nothing is deleted.`,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				reclone := fs.Bool("reclone", false, "move broken folders aside and clone them again")
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.Verify(ctx, s.env, s.repos, *reclone))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "createLocalBranches",
+			aliases: []commandAlias{{name: "init4"}},
+			summary: "create local BranchMain and BranchUse if missing",
+			help: `Create local branches for BranchMain and BranchUse from the default remote's
+tracking branches if they don't exist yet. The starting branch is checked out
+again afterwards.`,
+			setup: func(_ *flag.FlagSet) func(context.Context, *session, []string) error {
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.CreateLocalBranches(ctx, s.env, s.repos))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "audit",
+			usage:   "[--fix]",
+			summary: "compare the configured remotes with git remote -v",
+			help: `Compare every configured remote (alias and URL) with the remotes actually set
+up in each repo. Reports missing, extra and mismatched remotes. With --fix
+missing remotes are added and mismatched URLs are set to the configured URL.
+Extra remotes are only reported, never removed.`,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				fix := fs.Bool("fix", false, "add missing remotes and set-url mismatched ones")
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.Audit(ctx, s.env, s.repos, *fix))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "checkBranches",
+			usage:   "[--fix]",
+			summary: "find BranchMain/BranchUse that no longer exist on the remotes",
+			help: `Ask each repo's remotes for their branches and HEAD with git ls-remote.
+Reports repos where BranchMain is not on the upstream remote (the default
+remote if there is no upstream) or BranchUse is not on the default remote. ie
+upstream renamed master to main. With --fix the stale branches are rewritten
+in repos.jsonc to the remote's HEAD branch. BranchUse is only rewritten when
+it's the same as BranchMain.`,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				fix := fs.Bool("fix", false, "rewrite stale branches in repos.jsonc to the remote's HEAD")
+				return func(ctx context.Context, s *session, _ []string) error {
+					s.printReport(commands.CheckBranches(ctx, s.env, s.repos, *fix))
+					return nil
+				}
+			},
+		},
+		&command{
+			name:    "add",
+			usage:   "<name> --upstream URL [--mine URL] [--branch master] [--folder path] [--yolo] [--clone]",
+			summary: "add a repo to repos.jsonc",
+			help: `Add a new GitRepo entry to the end of repos.jsonc. Existing comments and
+formatting are kept. With --mine the fork is the "origin" remote and the
+default, otherwise the upstream is. The folder defaults to
+<yoloRoot>/<clone dir> for yolo repos, <submoduleRoot>/<clone dir> otherwise.
+See the settings in repos.jsonc. With --clone a yolo repo is cloned and its remotes set up right away.
+Refused if the name or folder is already used in any config file: an included
+file, repos.local.jsonc, or a repo for another machine.`,
+			minArgs:  1,
+			maxArgs:  1,
+			flagArgs: map[string]argKind{"folder": argDir},
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				upstream := fs.String("upstream", "", "URL of the upstream remote (required)")
+				mine := fs.String("mine", "", "URL of my fork")
+				branch := fs.String("branch", "", "BranchMain and BranchUse. default: the defaultBranch setting")
+				folder := fs.String("folder", "", "folder of the repo. default based on the clone URL")
+				yolo := fs.Bool("yolo", false, "a normal clone in notElpaYolo, not a git submodule")
+				clone := fs.Bool("clone", false, "clone the repo and set up remotes now. yolo only")
+				return func(ctx context.Context, s *session, args []string) error {
+					if *upstream == "" {
+						return errors.New("--upstream is required")
+					}
+					if *branch == "" {
+						*branch = s.env.Settings.DefaultBranch
+					}
+					repo := config.NewGitRepo(s.env.Settings, args[0], *upstream, *mine, *branch, *folder, *yolo)
+					// a name or folder used for another machine or in an include is taken too.
+					return commands.Add(ctx, s.env, s.allRepos(), &repo, *clone)
+				}
+			},
+		},
+		&command{
+			name:    "remove",
+			usage:   "<name> [--archive] [--archive-dir path] [--force]",
+			summary: "remove a repo from repos.jsonc, optionally archiving its folder",
+			help: `Delete the GitRepo entry from repos.jsonc. Existing comments and formatting
+are kept. With --archive the yolo folder is moved to the archive dir, but only
+if it has no uncommitted changes and no commits missing from every remote.
+--force archives anyway. Submodule folders are never touched, use git rm.`,
+			minArgs:  1,
+			maxArgs:  1,
+			args:     argRepoName,
+			flagArgs: map[string]argKind{"archive-dir": argDir},
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				archive := fs.Bool("archive", false, "move the yolo folder to the archive dir")
+				archiveDir := fs.String("archive-dir", "", "where archived folders go. default <yoloRoot>Archive")
+				force := fs.Bool("force", false, "archive even with uncommitted changes or unpushed commits")
+				return func(ctx context.Context, s *session, args []string) error {
+					if *archiveDir == "" {
+						*archiveDir = s.env.DefaultArchiveDir()
+					}
+					repo, err := s.findRepo(args[0])
+					if err != nil {
+						return err
+					}
+					return commands.Remove(ctx, s.env, repo, *archive, *archiveDir, *force)
+				}
+			},
+		},
+		&command{
+			name:    "discover",
+			usage:   "[dir] [--user name] [--depth 2] [--merge]",
+			summary: "find git repos on disk that are not in repos.jsonc",
+			help: `Scan dir (default the yoloRoot setting) for git repos that are not in
+repos.jsonc and print candidate GitRepo entries for them. The remotes and
+current branch are read from each repo. A remote owned by the fork user is
+given the Sym "mine", the others "upstream". The fork user defaults to the git
+config github.user, then to the most common owner of the "mine" remotes in
+repos.jsonc. With --merge the candidates are added to repos.jsonc.`,
+			maxArgs: 1,
+			args:    argDir,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				user := fs.String("user", "", "owner of my forks. ie github user name")
+				depth := fs.Int("depth", 2, "how many folders deep to look for repos")
+				merge := fs.Bool("merge", false, "add the candidates to repos.jsonc instead of printing them")
+				return func(ctx context.Context, s *session, args []string) error {
+					dir := s.env.Settings.YoloRoot
+					if len(args) > 0 {
+						dir = args[0]
+					}
+					// a folder configured for another machine is not a new candidate.
+					all := s.allRepos()
+					if *user == "" {
+						*user = commands.DefaultForkUser(ctx, all)
+					}
+					candidates, problems, err := commands.Discover(ctx, s.env, all, dir, *depth, *user)
+					if err != nil {
+						return err
+					}
+					return commands.PrintOrMergeCandidates(s.env, all, candidates, problems, *merge)
+				}
+			},
+		},
+		&command{
+			name:    "importSubmodules",
+			usage:   "[superproject] [--user name] [--merge]",
+			summary: "generate entries for the git submodules of a superproject",
+			help: `Read .gitmodules of the superproject (default the emacsDir setting) and
+print candidate GitRepo entries (isYolo false) for submodules whose folder is
+not in repos.jsonc yet. Relative submodule URLs are resolved against the
+superproject's origin. Remotes and the branch of checked out submodules are
+also read from disk. Syms are guessed like discover does. With --merge the
+candidates are added to repos.jsonc.`,
+			maxArgs: 1,
+			args:    argDir,
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				user := fs.String("user", "", "owner of my forks. ie github user name")
+				merge := fs.Bool("merge", false, "add the candidates to repos.jsonc instead of printing them")
+				return func(ctx context.Context, s *session, args []string) error {
+					superproject := s.env.Settings.EmacsDir
+					if len(args) > 0 {
+						superproject = args[0]
+					}
+					all := s.allRepos()
+					if *user == "" {
+						*user = commands.DefaultForkUser(ctx, all)
+					}
+					candidates, problems, err := commands.ImportSubmodules(ctx, s.env, all, superproject, *user)
+					if err != nil {
+						return err
+					}
+					return commands.PrintOrMergeCandidates(s.env, all, candidates, problems, *merge)
+				}
+			},
+		},
+		&command{
+			name:    "migrateToYolo",
+			usage:   "<name> [--folder path] [--dry-run] [--force]",
+			summary: "turn a submodule repo into a yolo clone",
+			help: `Move a repo from a git submodule of the superproject to a normal clone in
+the yoloRoot setting (or --folder). The clone is checked out at the same
+commit and branch as the submodule and gets all the configured remotes. Then
+the submodule is deinited and removed from the superproject, repos.jsonc is
+updated (folder, isYolo) and the new folder is added to the superproject's
+.gitignore if it's inside it and not ignored already. Refuses to run if the
+submodule has uncommitted changes or unpushed commits unless --force.
+--dry-run prints the steps without doing anything. The superproject changes
+are left for you to commit.`,
+			minArgs:  1,
+			maxArgs:  1,
+			args:     argRepoName,
+			flagArgs: map[string]argKind{"folder": argDir},
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				folder := fs.String("folder", "", "where to clone. default <yoloRoot>/<folder name>")
+				dryRun := fs.Bool("dry-run", false, "print the steps without doing them")
+				force := fs.Bool("force", false, "migrate even with uncommitted changes or unpushed commits")
+				return func(ctx context.Context, s *session, args []string) error {
+					repo, err := s.findRepo(args[0])
+					if err != nil {
+						return err
+					}
+					return commands.MigrateToYolo(ctx, s.env, repo, *folder, *dryRun, *force)
+				}
+			},
+		},
+		&command{
+			name:    "config",
+			usage:   "dump | convert --to jsonc|yaml|toml",
+			summary: "show the effective config, or convert the config file to another format",
+			help: `dump prints the config as loaded: the files in "include" first, then
+repos.jsonc, then repos.local.jsonc next to it if there is one. A repo named in a
+later file overrides the fields it sets, "disable" drops repos by name. The
+settings are resolved for this OS and the global flags. Each repo is commented
+with the files it comes from.
+
+convert prints the config file (just that file, not its includes) in another
+format. The format of a config file is by its extension: .jsonc, .yaml/.yml or
+.toml. Comments are not carried over. ie
+
+  ` + programName + ` config convert --to yaml > repos.yaml`,
+			minArgs:     1,
+			maxArgs:     1,
+			args:        argChoice,
+			choices:     []string{"dump", "convert"},
+			flagArgs:    map[string]argKind{"to": argChoice},
+			flagChoices: map[string][]string{"to": choiceNames(config.Formats)},
+			setup: func(fs *flag.FlagSet) func(context.Context, *session, []string) error {
+				to := fs.String("to", "", "convert: format to write. jsonc, yaml or toml")
+				return func(_ context.Context, s *session, args []string) error {
+					switch args[0] {
+					case "dump":
+						return config.Dump(os.Stdout, s.env.Settings, s.repos)
+					case "convert":
+						format, err := config.ParseFormat(*to)
+						if err != nil {
+							return err
+						}
+						src, err := os.ReadFile(configPath)
+						if err != nil {
+							return err
+						}
+						return config.Convert(os.Stdout, config.FormatOf(configPath), format, src)
+					}
+					return fmt.Errorf("unknown config action %q. expected dump or convert", args[0])
+				}
+			},
+		},
+		&command{
+			name:    "schema",
+			summary: "print the JSON Schema of repos.jsonc",
+			help: `Print a JSON Schema of the config for editors to validate and complete
+repos.jsonc. It's built from the config structs, the descriptions are their doc
+comments. Point the editor at the committed repos.schema.json with
+"$schema": "./repos.schema.json" at the top of repos.jsonc.`,
+			skipInit: true,
+			setup: func(_ *flag.FlagSet) func(context.Context, *session, []string) error {
+				return func(_ context.Context, _ *session, _ []string) error {
+					return config.WriteSchema(os.Stdout)
+				}
+			},
+		},
+		&command{
+			name:    "completion",
+			usage:   "bash|zsh|fish",
+			summary: "print a shell completion script",
+			help: `Print a completion script for the shell. It completes command names, flags,
+remote Syms and repo names. Repo names and Syms are read from repos.jsonc when
+the script is generated, so regenerate it after adding repos.
+
+  bash: ` + programName + ` completion bash > /etc/bash_completion.d/` + programName + `
+  zsh:  ` + programName + ` completion zsh > "${fpath[1]}/_` + programName + `"
+  fish: ` + programName + ` completion fish > ~/.config/fish/completions/` + programName + `.fish`,
+			minArgs: 1,
+			maxArgs: 1,
+			args:    argChoice,
+			choices: []string{"bash", "zsh", "fish"},
+			setup: func(_ *flag.FlagSet) func(context.Context, *session, []string) error {
+				return func(_ context.Context, s *session, args []string) error {
+					return writeCompletion(os.Stdout, args[0], newCompletionSpec(s.repos))
+				}
+			},
+		},
+		&command{
+			name:     "help",
+			usage:    "[command]",
+			summary:  "show help for a command",
+			maxArgs:  1,
+			args:     argCommand,
+			skipInit: true,
+			setup: func(_ *flag.FlagSet) func(context.Context, *session, []string) error {
+				return func(_ context.Context, _ *session, args []string) error {
+					if len(args) == 0 {
+						printCommandList(os.Stdout)
+						return nil
+					}
+					cmd, _ := findCommand(args[0])
+					if cmd == nil {
+						return fmt.Errorf("unknown command %q", args[0])
+					}
+					printCommandHelp(os.Stdout, cmd)
+					return nil
+				}
+			},
+		},
+	)
+	return cmds
+}
+
+// summary line of a generated remote command. ie fetchUpstream.
+func remoteCommandSummary(rc remoteCommand) string {
+	var remote string
+	switch rc.remoteType {
+	case commands.RemoteUpstream:
+		remote = `the "upstream" remote`
+	case commands.RemoteMine:
+		remote = `the "mine" remote`
+	default:
+		remote = "the default remote"
+	}
+	if rc.op == "diff" {
+		return "list repos with new code in " + remote
+	}
+	return rc.op + " " + remote + " of each repo"
+}
+
+// register the --sym and --all-remotes flags shared by fetch and diff.
+// returns a func to resolve the flags to a RemoteType after parsing.
+// With no flags the default remote is used.
+func addRemoteFlags(fs *flag.FlagSet) func() (commands.RemoteType, string, error) {
+	sym := fs.String("sym", "", "use the remote with this Sym. repos without it are skipped")
+	allRemotes := fs.Bool("all-remotes", false, "use every configured remote")
+	return func() (commands.RemoteType, string, error) {
+		switch {
+		case *sym != "" && *allRemotes:
+			return 0, "", errors.New("--sym and --all-remotes are mutually exclusive")
+		case *sym != "":
+			return commands.RemoteSym, *sym, nil
+		case *allRemotes:
+			return commands.RemoteAll, "", nil
+		}
+		return commands.RemoteDefault, "", nil
+	}
+}
+
+// register the --skip-unchanged flag of the fetch commands.
+func addSkipUnchangedFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("skip-unchanged", false,
+		"check the remote's branch with git ls-remote first. skip the fetch if it didn't move")
+}
+
+// find a command by name or alias. The alias is nil if found by name.
+func findCommand(name string) (*command, *commandAlias) {
+	for _, cmd := range cliCommands() {
+		if cmd.name == name {
+			return cmd, nil
+		}
+		for i := range cmd.aliases {
+			if cmd.aliases[i].name == name {
+				return cmd, &cmd.aliases[i]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// the settings given by the global flags. They override the settings of repos.jsonc.
+var cliSettings config.Settings
+
+// set by the -v global flag. reports also list the repos inactive on this machine.
+var verbose bool
+
+// what the values of the global flags are, by flag name. used by shell completion.
+var globalFlagArgs = map[string]argKind{
+	"config":    argFile,
+	"yolo-root": argDir,
+	"merge-sym": argSym,
+	"output":    argChoice,
+}
+
+// fixed values of the global flags that are argChoice. used by shell completion.
+var globalFlagChoices = map[string][]string{"output": {"text", "json"}}
+
+// the values of an enum as strings. ie the clone modes for shell completion.
+func choiceNames[T ~string](values []T) []string {
+	names := make([]string, 0, len(values))
+	for _, v := range values {
+		names = append(names, string(v))
+	}
+	return names
+}
+
+// the flags given before the command name. ie "gitFetchHelper --jobs 4 fetch".
+func globalFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet(programName, flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // print errors and usage ourselves
+	fs.StringVar(&configPath, "config", configPath, "path of the config file. default the 1st of repos.jsonc, repos.yaml, repos.yml, repos.toml")
+	fs.IntVar(&cliSettings.Jobs, "jobs", 0, "max # of git commands running at once")
+	fs.StringVar(&cliSettings.Timeout, "timeout", "", "kill git commands running longer than this. ie 2m")
+	fs.StringVar(&cliSettings.DefaultBranch, "default-branch", "", "BranchMain of repos that don't set it")
+	fs.StringVar(&cliSettings.MergeSym, "merge-sym", "", "Sym of the remote mergeMine merges")
+	fs.StringVar(&cliSettings.YoloRoot, "yolo-root", "", "where yolo repos are cloned")
+	fs.StringVar(&cliSettings.Output, "output", "", "report format: text or json")
+	fs.BoolVar(&verbose, "v", false, "verbose. also report the repos inactive on this machine")
+	return fs
+}
+
+// run the command line. args excludes the program name. Returns the exit code.
+func runCLI(ctx context.Context, args []string) int {
+	cliSettings, verbose = config.Settings{}, false
+	gfs := globalFlags()
+	// stops at the command name. global flags after it are the command's flags.
+	err := gfs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandList(os.Stdout)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n\n", err.Error())
+		printCommandList(os.Stderr)
+		return 2
+	}
+	configGiven := false
+	gfs.Visit(func(f *flag.Flag) { configGiven = configGiven || f.Name == "config" })
+	if !configGiven {
+		configPath = config.FindDefault()
+	}
+	args = gfs.Args()
+	if len(args) == 0 {
+		printCommandList(os.Stderr)
+		return 2
+	}
+	cmd, alias := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "error: unknown command %q\n\n", args[0])
+		printCommandList(os.Stderr)
+		return 2
+	}
+	cmdArgs := args[1:]
+	if alias != nil {
+		cmdArgs = append(append([]string{}, alias.args...), cmdArgs...)
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard) // print errors and usage ourselves
+	run := cmd.setup(fs)
+	positional, err := parseInterspersed(fs, cmdArgs)
+	if errors.Is(err, flag.ErrHelp) {
+		printCommandHelp(os.Stdout, cmd)
+		return 0
+	}
+	if err == nil {
+		err = checkArgCount(cmd, positional)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n\n", err.Error())
+		printCommandHelp(os.Stderr, cmd)
+		return 2
+	}
+
+	var s *session
+	if !cmd.skipInit {
+		if s, err = loadSession(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+			return 1
+		}
+	}
+	if err = run(ctx, s, positional); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+		return 1
+	}
+	return 0
+}
+
+// parse flags that may be mixed in with positional args. ie "add magit --yolo".
+// The standard flag package stops at the first positional arg.
+// Everything after a "--" terminator is positional.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var afterTerminator []string
+	for i, arg := range args {
+		if arg == "--" {
+			afterTerminator = args[i+1:]
+			args = args[:i]
+			break
+		}
+	}
+	positional := make([]string, 0, 2)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return append(positional, afterTerminator...), nil
+}
+
+// validate the number of positional args for cmd.
+func checkArgCount(cmd *command, args []string) error {
+	if len(args) < cmd.minArgs {
+		return fmt.Errorf("%s: expected at least %d argument(s), got %d", cmd.name, cmd.minArgs, len(args))
+	}
+	if cmd.maxArgs >= 0 && len(args) > cmd.maxArgs {
+		return fmt.Errorf("%s: unexpected argument(s): %s", cmd.name, strings.Join(args[cmd.maxArgs:], " "))
+	}
+	return nil
+}
+
+// print the list of commands with their summaries.
+func printCommandList(w io.Writer) {
+	cmds := cliCommands()
+	width := 0
+	for _, cmd := range cmds {
+		width = max(width, len(cmd.name))
+	}
+	fmt.Fprintf(w, "usage: %s [global flags] <command> [flags] [args]\n\ncommands:\n", programName)
+	for _, cmd := range cmds {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nglobal flags, override the settings of the config file:\n")
+	gfs := globalFlags()
+	gfs.SetOutput(w)
+	gfs.PrintDefaults()
+	fmt.Fprintf(w, "\nRun \"%s help <command>\" for details on a command.\n", programName)
+}
+
+// print the usage, aliases, help text and flags of cmd.
+func printCommandHelp(w io.Writer, cmd *command) {
+	fmt.Fprintf(w, "usage: %s %s", programName, cmd.name)
+	if cmd.usage != "" {
+		fmt.Fprintf(w, " %s", cmd.usage)
+	}
+	fmt.Fprintln(w)
+	if len(cmd.aliases) > 0 {
+		names := make([]string, 0, len(cmd.aliases))
+		for _, a := range cmd.aliases {
+			if len(a.args) > 0 {
+				names = append(names, fmt.Sprintf("%s (%s)", a.name, strings.Join(a.args, " ")))
+			} else {
+				names = append(names, a.name)
+			}
+		}
+		fmt.Fprintf(w, "aliases: %s\n", strings.Join(names, ", "))
+	}
+	fmt.Fprintf(w, "\n%s\n", cmd.summary)
+	if cmd.help != "" {
+		fmt.Fprintf(w, "\n%s\n", cmd.help)
+	}
+
+	// a throw away flag set just to list the flags.
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	cmd.setup(fs)
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintf(w, "\nflags:\n")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	yolo := fs.Bool("yolo", false, "")
+	branch := fs.String("branch", "", "")
+	got, err := parseInterspersed(fs, []string{"magit", "--yolo", "--branch", "main", "extra", "--", "-notAFlag"})
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	if !*yolo || *branch != "main" {
+		t.Fatalf("got: yolo %t branch %s. wanted yolo true branch main", *yolo, *branch)
+	}
+	want := []string{"magit", "extra", "-notAFlag"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("got: %v. wanted %v", got, want)
+	}
+
+	// unknown flag is an error
+	fs = flag.NewFlagSet("fetch", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if _, err = parseInterspersed(fs, []string{"--bogus"}); err == nil {
+		t.Fatalf("wanted an error for unknown flag")
+	}
+}
+
+func TestFindCommandAlias(t *testing.T) {
+	cmd, alias := findCommand("init3Shallow")
+	if cmd == nil || cmd.name != "cloneYoloRepos" {
+		t.Fatalf("got: %v. wanted cloneYoloRepos", cmd)
+	}
+	if alias == nil || strings.Join(alias.args, " ") != "--mode shallow" {
+		t.Fatalf("got: %v. wanted --mode shallow preset", alias)
+	}
+
+	cmd, alias = findCommand("diffMine")
+	if cmd == nil || alias != nil {
+		t.Fatalf("got: %v %v. wanted diffMine by name", cmd, alias)
+	}
+
+	if cmd, _ = findCommand("bogus"); cmd != nil {
+		t.Fatalf("got: %v. wanted nil", cmd)
+	}
+}
+
+// names and aliases must be unique or findCommand would shadow a command.
+func TestCommandNamesUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, cmd := range cliCommands() {
+		names := []string{cmd.name}
+		for _, a := range cmd.aliases {
+			names = append(names, a.name)
+		}
+		for _, name := range names {
+			if seen[name] {
+				t.Fatalf("duplicate command name: %s", name)
+			}
+			seen[name] = true
+		}
+	}
+}
+
+func TestCheckArgCount(t *testing.T) {
+	cmd := &command{name: "help", maxArgs: 1}
+	if err := checkArgCount(cmd, []string{"fetch"}); err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	if err := checkArgCount(cmd, []string{"fetch", "diff"}); err == nil {
+		t.Fatalf("wanted an error for too many args")
+	}
+	cmd = &command{name: "remove", minArgs: 1, maxArgs: -1}
+	if err := checkArgCount(cmd, nil); err == nil {
+		t.Fatalf("wanted an error for too few args")
+	}
+}
+
+func TestGlobalFlags(t *testing.T) {
+	savedPath := configPath
+	defer func() { configPath, cliSettings = savedPath, config.Settings{} }()
+
+	fs := globalFlags()
+	err := fs.Parse([]string{"--config", "/tmp/r.jsonc", "--jobs", "4", "--output", "json", "fetch", "--sym", "mine"})
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	if configPath != "/tmp/r.jsonc" || cliSettings.Jobs != 4 || cliSettings.Output != "json" {
+		t.Errorf("got: config %s, settings %+v", configPath, cliSettings)
+	}
+	// parsing stops at the command name. the rest are the command's args.
+	if args := fs.Args(); strings.Join(args, " ") != "fetch --sym mine" {
+		t.Errorf("got: %v. wanted the command and its flags", args)
+	}
+}
@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// Detect drift between the remotes in repos.jsonc and the remotes actually set up in
+// each repo (git remote -v).
+
+type driftKind int
+
+const (
+	// configured but not set up in the repo.
+	driftMissing driftKind = iota + 1
+	// set up in the repo but not configured.
+	driftExtra
+	// alias is set up in the repo with a different URL.
+	driftMismatch
+)
+
+func (k driftKind) String() string {
+	switch k {
+	case driftMissing:
+		return "missing"
+	case driftExtra:
+		return "extra"
+	case driftMismatch:
+		return "mismatched"
+	}
+	return "unknown"
+}
+
+// a difference between a configured remote and the actual remote of a repo.
+type remoteDrift struct {
+	kind  driftKind
+	alias string
+	// URL in repos.jsonc. empty for driftExtra.
+	configURL string
+	// URL in the repo. empty for driftMissing.
+	actualURL string
+}
+
+func (d remoteDrift) String() string {
+	switch d.kind {
+	case driftMissing:
+		return fmt.Sprintf("missing remote %s %s", d.alias, d.configURL)
+	case driftExtra:
+		return fmt.Sprintf("extra remote %s %s", d.alias, d.actualURL)
+	}
+	// note: config: and actual: are same len for visual alignment of url strings.
+	return fmt.Sprintf("mismatched remote %s\n    config: %s\n    actual: %s", d.alias, d.configURL, d.actualURL)
+}
+
+// compare the configured remotes with the actual remotes of a repo. Configured remotes
+// sharing an alias (ie "mine" and "upstream" are both "origin" for my own projects) are
+// checked once, the 1st one wins. Results are in config order, then the extras.
+func compareRemotes(cfg, actual []config.Remote) []remoteDrift {
+	actualURLs := make(map[string]string, len(actual))
+	for _, rem := range actual {
+		actualURLs[rem.Alias] = rem.URL
+	}
+	drifts := make([]remoteDrift, 0, 2)
+	configured := make(map[string]bool, len(cfg))
+	for _, rem := range cfg {
+		if configured[rem.Alias] {
+			continue
+		}
+		configured[rem.Alias] = true
+		url, ok := actualURLs[rem.Alias]
+		switch {
+		case !ok:
+			drifts = append(drifts, remoteDrift{kind: driftMissing, alias: rem.Alias, configURL: rem.URL})
+		case url != rem.URL:
+			drifts = append(drifts, remoteDrift{kind: driftMismatch, alias: rem.Alias, configURL: rem.URL, actualURL: url})
+		}
+	}
+	for _, rem := range actual {
+		if !configured[rem.Alias] {
+			drifts = append(drifts, remoteDrift{kind: driftExtra, alias: rem.Alias, actualURL: rem.URL})
+		}
+	}
+	return drifts
+}
+
+// titles of the audit report sections.
+const (
+	sectionDrift = "DRIFT"
+	// only with fix.
+	sectionFixed = "FIXED"
+)
+
+// check every repo's remotes against repos.jsonc. With fix, missing remotes are added and
+// mismatched URLs are set to the configured URL. Extra remotes are only reported.
+func Audit(ctx context.Context, env *Env, repos []config.GitRepo, fix bool) report.Report {
+	return auditRunner(env, fix).run(ctx, repos)
+}
+
+// AuditEach audits like Audit but sends the result of each repo as soon as it's done.
+func AuditEach(ctx context.Context, env *Env, repos []config.GitRepo, fix bool) <-chan RepoResult {
+	return auditRunner(env, fix).each(ctx, repos)
+}
+
+func auditRunner(env *Env, fix bool) runner {
+	sections := []string{sectionDrift}
+	if fix {
+		sections = append(sections, sectionFixed)
+	}
+	return runner{
+		sections: sections,
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return audit(ctx, env, i, repo, fix)
+		},
+		summary: func(t tally) string {
+			// print # of repos checked, duration
+			return fmt.Sprintf("Audited remotes of %d repos. time elapsed: %v", t.repos, t.elapsed)
+		},
+	}
+}
+
+func audit(ctx context.Context, env *Env, i int, repo config.GitRepo, fix bool) RepoResult {
+	result := newResult(i, repo)
+
+	actual, err := gitops.Remotes(ctx, env.expand(repo.Folder))
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+	drifts := compareRemotes(repo.Remotes, actual)
+	if len(drifts) == 0 {
+		return result // no reporting needed for "normal" case when remotes match.
+	}
+	for _, d := range drifts {
+		result.add(sectionDrift, fmt.Sprintf("%d: %s %s\n", i, repo.Folder, d))
+	}
+
+	if !fix {
+		return result
+	}
+	for _, d := range drifts {
+		dir := env.expand(repo.Folder)
+		var cmd *exec.Cmd
+		switch d.kind {
+		case driftMissing:
+			cmd = gitops.Command(ctx, dir, "remote", "add", d.alias, d.configURL)
+		case driftMismatch:
+			cmd = gitops.Command(ctx, dir, "remote", "set-url", d.alias, d.configURL)
+		default:
+			continue // extra remotes may be in use. leave them alone.
+		}
+		output, err := gitops.CombinedOutput(cmd)
+		if err != nil {
+			result.fail(fmt.Sprintf("%d: %s %v %s %s\n", i, repo.Folder, cmd.Args, err.Error(), output))
+			continue
+		}
+		result.add(sectionFixed, fmt.Sprintf("%d: %s %v\n", i, repo.Folder, cmd.Args))
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func TestCompareRemotes(t *testing.T) {
+	cfg := []config.Remote{
+		{Sym: "mine", URL: "git@github.com:me/proj.git", Alias: "origin"},
+		{Sym: "upstream", URL: "git@github.com:me/proj.git", Alias: "origin"}, // same alias, checked once
+		{Sym: "them", URL: "https://github.com/them/proj", Alias: "them"},
+		{Sym: "old", URL: "https://example.com/proj", Alias: "old"},
+	}
+	actual := []config.Remote{
+		{URL: "git@github.com:me/proj.git", Alias: "origin"},
+		{URL: "https://github.com/them/proj.git", Alias: "them"},
+		{URL: "https://github.com/other/proj", Alias: "other"},
+	}
+	want := []remoteDrift{
+		{kind: driftMismatch, alias: "them", configURL: "https://github.com/them/proj", actualURL: "https://github.com/them/proj.git"},
+		{kind: driftMissing, alias: "old", configURL: "https://example.com/proj"},
+		{kind: driftExtra, alias: "other", actualURL: "https://github.com/other/proj"},
+	}
+	if got := compareRemotes(cfg, actual); !reflect.DeepEqual(got, want) {
+		t.Errorf("compareRemotes() =\n%v\nwant\n%v", got, want)
+	}
+	if got := compareRemotes(cfg[:1], actual[:1]); len(got) != 0 {
+		t.Errorf("compareRemotes() on matching remotes = %v, want none", got)
+	}
+}
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// Detect configured branches that no longer exist on the remotes. ie upstream renamed
+// master to main so BranchMain is stale and fetch/diff fail with unknown revision.
+
+// compare BranchMain with the upstream's refs and BranchUse with the default remote's
+// refs. Returns a message per missing branch, and the repos.jsonc fields to fix them.
+// BranchMain is fixed to the upstream's HEAD. BranchUse only when it's the same branch
+// as BranchMain, a custom branch like "mine" can't be guessed.
+func staleBranches(repo *config.GitRepo, mainRemote config.Remote, mainRefs gitops.LsRemoteRefs,
+	useRemote config.Remote, useRefs gitops.LsRemoteRefs,
+) ([]string, map[string]string) {
+	msgs := make([]string, 0, 2)
+	fields := make(map[string]string, 2)
+	if _, ok := mainRefs.Branches[repo.BranchMain]; !ok {
+		msgs = append(msgs, fmt.Sprintf("branchMain %s is not on %s. its HEAD is %q",
+			repo.BranchMain, mainRemote.Alias, mainRefs.Head))
+		if mainRefs.Head != "" {
+			fields["branchMain"] = config.JSONString(mainRefs.Head)
+		}
+	}
+	if _, ok := useRefs.Branches[repo.BranchUse]; !ok {
+		msgs = append(msgs, fmt.Sprintf("branchUse %s is not on %s. its HEAD is %q",
+			repo.BranchUse, useRemote.Alias, useRefs.Head))
+		if repo.BranchUse == repo.BranchMain && useRefs.Head != "" {
+			fields["branchUse"] = config.JSONString(useRefs.Head)
+		}
+	}
+	return msgs, fields
+}
+
+// title of the check branches report section with the missing branches.
+const sectionStale = "STALE branches"
+
+// check BranchMain and BranchUse of every repo exist on the remotes. With fix, stale
+// branches are rewritten in repos.jsonc to the remote's HEAD branch.
+func CheckBranches(ctx context.Context, env *Env, repos []config.GitRepo, fix bool) report.Report {
+	sections := []string{sectionStale}
+	if fix {
+		sections = append(sections, sectionFixed)
+	}
+	mutConfig := sync.Mutex{} // repos share config files. 1 write at a time.
+	return runner{
+		sections: sections,
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			result, fields := checkBranch(ctx, env, i, repo)
+			if !fix || len(fields) == 0 {
+				return result
+			}
+			mutConfig.Lock()
+			defer mutConfig.Unlock()
+			// a field set in an override is fixed there, fixing Source would change nothing.
+			paths, byFile, err := fieldsByFile(&repo, fields)
+			if err != nil {
+				result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+				return result
+			}
+			for _, path := range paths {
+				if err := config.SetRepoFields(path, repo.Name, byFile[path]); err != nil {
+					result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+					continue
+				}
+				result.add(sectionFixed, fmt.Sprintf("%d: %s %v in %s\n", i, repo.Folder, byFile[path], path))
+			}
+			return result
+		},
+		summary: func(t tally) string {
+			// print # of repos checked, duration
+			return fmt.Sprintf("Checked branches of %d repos. time elapsed: %v", t.repos, t.elapsed)
+		},
+	}.run(ctx, repos)
+}
+
+// group the fields to fix by the config file that sets them. Returns the files in the
+// order they are first needed so the report is the same every run.
+func fieldsByFile(repo *config.GitRepo, fields map[string]string) ([]string, map[string]map[string]string, error) {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	paths := make([]string, 0, 1)
+	byFile := make(map[string]map[string]string, 1)
+	for _, key := range keys {
+		path, err := config.FieldSource(repo, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if byFile[path] == nil {
+			paths = append(paths, path)
+			byFile[path] = make(map[string]string, len(fields))
+		}
+		byFile[path][key] = fields[key]
+	}
+	return paths, byFile, nil
+}
+
+// check the branches of repo. Returns the repos.jsonc fields to fix the stale branches.
+func checkBranch(ctx context.Context, env *Env, i int, repo config.GitRepo) (RepoResult, map[string]string) {
+	result := newResult(i, repo)
+
+	useRemote, err := repo.RemoteDefault()
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result, nil
+	}
+	// BranchMain is the upstream's branch. my own projects may not have an upstream.
+	mainRemote, err := repo.RemoteUpstream()
+	if err != nil {
+		mainRemote = useRemote
+	}
+
+	// ls-remote by URL so it works even if the remote isn't set up in the repo yet.
+	useRefs, err := gitops.LsRemote(ctx, env.expand(repo.Folder), useRemote.URL)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result, nil
+	}
+	mainRefs := useRefs
+	if mainRemote.URL != useRemote.URL {
+		mainRefs, err = gitops.LsRemote(ctx, env.expand(repo.Folder), mainRemote.URL)
+		if err != nil {
+			result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+			return result, nil
+		}
+	}
+
+	msgs, fields := staleBranches(&repo, mainRemote, mainRefs, useRemote, useRefs)
+	if len(msgs) == 0 {
+		return result, nil // no reporting needed for "normal" case when branches exist.
+	}
+	for _, msg := range msgs {
+		result.add(sectionStale, fmt.Sprintf("%d: %s %s\n", i, repo.Folder, msg))
+	}
+	return result, fields
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+)
+
+func TestStaleBranches(t *testing.T) {
+	upstream := config.Remote{Sym: "upstream", Alias: "upstream"}
+	mine := config.Remote{Sym: "mine", Alias: "origin"}
+	renamed := gitops.LsRemoteRefs{Head: "main", Branches: map[string]string{"main": "a"}}
+	fork := gitops.LsRemoteRefs{Head: "master", Branches: map[string]string{"master": "a", "mine": "b"}}
+
+	tests := []struct {
+		name       string
+		branchMain string
+		branchUse  string
+		useRefs    gitops.LsRemoteRefs
+		wantMsgs   int
+		wantFields map[string]string
+	}{
+		{"up to date", "main", "main", renamed, 0, map[string]string{}},
+		{"renamed", "master", "master", renamed, 2,
+			map[string]string{"branchMain": `"main"`, "branchUse": `"main"`}},
+		{"custom branchUse kept", "master", "mine", fork, 1, map[string]string{"branchMain": `"main"`}},
+		{"custom branchUse missing", "main", "gone", fork, 1, map[string]string{}},
+	}
+	for _, tt := range tests {
+		repo := config.GitRepo{BranchMain: tt.branchMain, BranchUse: tt.branchUse}
+		msgs, fields := staleBranches(&repo, upstream, renamed, mine, tt.useRefs)
+		if len(msgs) != tt.wantMsgs {
+			t.Errorf("%s: got msgs %v, want %d", tt.name, msgs, tt.wantMsgs)
+		}
+		if !reflect.DeepEqual(fields, tt.wantFields) {
+			t.Errorf("%s: got fields %v, want %v", tt.name, fields, tt.wantFields)
+		}
+	}
+}
+
+func TestCheckBranchesFixOverride(t *testing.T) {
+	dir := t.TempDir()
+	up := filepath.Join(dir, "up")
+	gitT(t, dir, "init", "-q", "-b", "main", up)
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "first")
+
+	// branchMain is stale in the overlay, the main file's value is already overridden.
+	cfgPath := filepath.Join(dir, "repos.jsonc")
+	writeFileT(t, cfgPath, `{ "repos": [
+  {"name": "foo", "folder": "`+up+`", "branchMain": "main", "branchUse": "main",
+   "remotes": [{"sym": "upstream", "url": "`+up+`", "alias": "origin"}], "remoteDefault": "upstream"}
+] }`)
+	localPath := filepath.Join(dir, "repos.local.jsonc")
+	writeFileT(t, localPath, `{ "repos": [ {"name": "foo", "branchMain": "master"} ] }`)
+
+	env := &Env{Home: home, ConfigPath: cfgPath, Out: io.Discard}
+	rep := CheckBranches(context.Background(), env, []config.GitRepo{loadRepoT(t, cfgPath, "foo")}, true)
+	for _, sec := range rep.Sections {
+		if sec.Title == sectionFailures && len(sec.Lines) > 0 {
+			t.Fatalf("CheckBranches() failed: %v", sec.Lines)
+		}
+	}
+	if repo := loadRepoT(t, cfgPath, "foo"); repo.BranchMain != "main" {
+		t.Errorf("branchMain is %s after the fix, want main", repo.BranchMain)
+	}
+	src, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), `"branchMain": "main"`) {
+		t.Errorf("%s was changed, want only the overlay fixed:\n%s", cfgPath, src)
+	}
+}
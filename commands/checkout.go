@@ -0,0 +1,227 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// titles of the checkout report sections.
+const (
+	sectionBranchesCreated = "Repos with local branches created"
+	// only includes repos that needed a switch to UseBranch or a reset to the remote.
+	sectionBranchChanges = "Branch change actions"
+)
+
+// create local branches (ie featureX) for each remote tracking branch (ie origin/featureX).
+// For all remote tracking of the default remote.
+// this is needed for things like listReposWithUpstreamCodeToMerge() to work as it diffs
+// the "local" branch (at least currently), and a differnet branch may be checked out (featureQ).
+func CreateLocalBranches(ctx context.Context, env *Env, repos []config.GitRepo) report.Report {
+	return runner{
+		sections: []string{sectionBranchesCreated},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return createLocalBranchesForRepo(ctx, env, i, repo)
+		},
+		summary: func(t tally) string {
+			// print # of repos checked, duration
+			return fmt.Sprintf("Checked for existence of local branches in %d repos, create if not exist. time elapsed: %v",
+				t.repos, t.elapsed)
+		},
+	}.run(ctx, repos)
+}
+
+// create "local" branches if they do not exist yet.
+func createLocalBranchesForRepo(ctx context.Context, env *Env, index int, repo config.GitRepo) RepoResult {
+	result := newResult(index, repo)
+
+	// get current checked out branch name.
+	// It may be the configured repo.MainBranch, repo.BranchUse (ie "mine"), or empty "" (detached head)
+	// we will need to checkout this branch at the end as the act of creating branches will switch to them
+	startingBranch, err := gitops.CurrentBranch(ctx, env.expand(repo.Folder))
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", index, repo.Folder, "problem getting current branch name: "+err.Error()))
+		return result
+	}
+
+	// default remote repo is using. usually my fork. sometimes direclty use the upstream.
+	remoteDefault, err := repo.RemoteDefault()
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", index, repo.Folder, err.Error()))
+		return result
+	}
+
+	// // 1. get all remote branch names from the default remote
+	// trackingBranches, err := TrackingBranches(repo.Folder, remoteDefault.Alias)
+	// if err != nil {
+	// 	result.fail(fmt.Sprintf("%d: %s %s\n", index, repo.Folder, err.Error()))
+	// 	return result
+	// }
+	// if len(trackingBranches) == 0 {
+	// 	return result // no branches to checkout!
+	// }
+
+	checkoutCnt := 0
+	var collectOutput strings.Builder
+	collectOutput.Grow(100 * 2) // allocate enough space up front
+
+	// 2. for each remote tracking branch: create local branch if it does not exist
+	// actually don't bother creating all remote tracking remoteBranches
+	// only 2: repo.BranchMain, repo.BranchUse. if i need an odd branch I can manullay create as needed.
+	remoteBranches := make([]string, 0, 2)
+	remoteBranches = append(remoteBranches, remoteDefault.Alias+"/"+repo.BranchMain)
+	// in theory BranchUse should always exist locally. And if BranchMain is the same branch then ditto.
+	// but we will proceed with the checks and creation attempts anyway to fill in any gaps where
+	// a branch doesn't exist for some reason.
+	if repo.BranchMain != repo.BranchUse {
+		remoteBranches = append(remoteBranches, remoteDefault.Alias+"/"+repo.BranchUse)
+	}
+	// check for, then create the branches
+	for i := 0; i < len(remoteBranches); i++ {
+		// remoteBranchName should be something like "origin/master"
+		remoteBranchName := remoteBranches[i]
+		// should be something like "master"
+		branchName := gitops.RemoveRemoteFromBranchName(remoteBranchName)
+		hasBranch, _ := gitops.HasLocalBranch(ctx, env.expand(repo.Folder), branchName)
+		if hasBranch {
+			continue // local branch already exists. no need to create it.
+		}
+		// create branch!
+		// git checkout --track origin/featureX
+		cmd := gitops.Command(ctx, env.expand(repo.Folder), "checkout", "--track", remoteBranchName)
+		stdout, errOut := gitops.CombinedOutput(cmd)
+		if errOut != nil {
+			result.fail(fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, errOut.Error()))
+			return result
+		}
+		collectOutput.WriteString(fmt.Sprintf("%d: %s %v %s\n",
+			i, repo.Folder, cmd.Args, string(stdout)))
+		checkoutCnt++
+	}
+	if checkoutCnt == 0 {
+		return result // don't write to the "success" report if we didn't do anything
+	}
+	// successfully checked out 1 or more branches
+	result.add(sectionBranchesCreated, collectOutput.String())
+
+	// 3. finally switch back to the starting branch. When creating "local" branches we
+	// also checked them out!
+	wasDetachedHead := startingBranch == ""
+	if wasDetachedHead {
+		// if we were in a detached head state, just stay where we are.
+		// TODO: remember commit and switch back to commit of detatched head state
+		return result
+	}
+	// git checkout mine
+	cmd := gitops.Command(ctx, env.expand(repo.Folder), "checkout", startingBranch)
+	_, err = gitops.CombinedOutput(cmd)
+	// possible for this function to be a success with local branch creation, but
+	// fail when going back to starting branch
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %v %s\n", index, repo.Folder, cmd.Args, err.Error()))
+	}
+	return result
+}
+
+// Checkout the "UseBranch" for each git submodule.
+// Useful after a fresh emacs config clone to a new computer to avoid detached head state.
+func SwitchToBranches(ctx context.Context, env *Env, repos []config.GitRepo) report.Report {
+	return switchRunner(env).run(ctx, repos)
+}
+
+// SwitchToBranchesEach switches like SwitchToBranches but sends the result of each repo as
+// soon as it's done.
+func SwitchToBranchesEach(ctx context.Context, env *Env, repos []config.GitRepo) <-chan RepoResult {
+	return switchRunner(env).each(ctx, repos)
+}
+
+func switchRunner(env *Env) runner {
+	return runner{
+		sections: []string{sectionBranchChanges},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return switchToBranch(ctx, env, i, repo)
+		},
+		summary: func(t tally) string {
+			// print # of repos checked, duration
+			return fmt.Sprintf("Checked for UseBranch on %d repos. time elapsed: %v", t.repos, t.elapsed)
+		},
+	}
+}
+
+// Checkout the "UseBranch" for a git repo. i is the position of the repo, shown in the report.
+func switchToBranch(ctx context.Context, env *Env, i int, repo config.GitRepo) RepoResult {
+	result := newResult(i, repo)
+
+	// get current checked out branch name.
+	// It may be the configured repo.MainBranch, or custom "mine", or empty "" (detached head)
+	branchName, err := gitops.CurrentBranch(ctx, env.expand(repo.Folder))
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, "problem getting current branch name: "+err.Error()))
+		return result
+	}
+
+	remoteDefault, err := repo.RemoteDefault()
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+	// switch to branch if not already on it.
+	if branchName != repo.BranchUse {
+		hasLocalBranch, err2 := gitops.HasLocalBranch(ctx, env.expand(repo.Folder), repo.BranchUse)
+		if err2 != nil {
+			result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, "problem checking for local branch existence: "+err2.Error()))
+			return result
+		}
+		// Action #1
+		// prepare branch switch command. example: git checkout --track origin/master
+		var cmd *exec.Cmd
+		if hasLocalBranch {
+			cmd = gitops.Command(ctx, env.expand(repo.Folder), "checkout", repo.BranchUse)
+		} else {
+			cmd = gitops.Command(ctx, env.expand(repo.Folder), "checkout", "--track", remoteDefault.Alias+"/"+repo.BranchUse)
+		}
+		// Run branch switch!
+		_, err2 = gitops.CombinedOutput(cmd)
+		if err2 != nil {
+			result.fail(fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, err2.Error()))
+			return result
+		}
+
+		// track the fact we just switched branches
+		result.add(sectionBranchChanges, fmt.Sprintf("%d: %s %v\n",
+			i, repo.Folder, cmd.Args))
+	}
+
+	// make sure branch is up to date with origin
+	hashLocalUseBranch, err := gitops.Hash(ctx, env.expand(repo.Folder), repo.BranchUse)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+	hashRemoteUseBranch, err := gitops.Hash(ctx, env.expand(repo.Folder), remoteDefault.Alias+"/"+repo.BranchUse)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+
+	if hashLocalUseBranch != hashRemoteUseBranch {
+		// Action #2.
+		// force reset to remote version of branch
+		cmd := gitops.Command(ctx, env.expand(repo.Folder), "reset", "--hard", remoteDefault.Alias+"/"+repo.BranchUse)
+		// Run branch switch!
+		_, err = gitops.CombinedOutput(cmd)
+		if err != nil {
+			result.fail(fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, err.Error()))
+			return result
+		}
+		// track the fact we just reset the branch to match origin
+		result.add(sectionBranchChanges, fmt.Sprintf("%d: %s %v\n",
+			i, repo.Folder, cmd.Args))
+	}
+	return result
+}
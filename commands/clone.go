@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// the args of the git clone command for mode. The target folder is given explicitly so
+// the clone doesn't end up in a folder named after the URL when they differ.
+func cloneArgs(mode config.CloneMode, branch, url, folder string) []string {
+	args := []string{"clone"}
+	switch mode {
+	case config.CloneShallow:
+		// git clone --depth 1 --branch master --no-single-branch remoteUrl
+		// using a shallow clone for performance. But still get the tip of each branch
+		// with "--no-single-branch" to avoid a headache later when trying to switch to
+		// other branches. git makes you go through convoluted steps if you don't get
+		// the branches during the clone.
+		// for full history run: unshallow
+		args = append(args, "--depth", "1", "--no-single-branch")
+	case config.CloneBlobless:
+		// git clone --filter=blob:none --branch master remoteUrl
+		// all commits and trees so merges/rebases work. blobs are fetched on demand.
+		args = append(args, "--filter=blob:none")
+	}
+	// full clones are the default. The clone step in theory only executes 1 time ever
+	// on first setup of a new computer, so it's OK if it's slower.
+	return append(args, "--branch", branch, url, folder)
+}
+
+// titles of the clone and unshallow report sections.
+const (
+	// only includes repos that needed to be cloned
+	sectionCloned      = "Clones performed"
+	sectionUnshallowed = "Unshallowed"
+)
+
+// for each "yolo" repo, clone it if it does not yet exist
+// NOTE: git submodules dont' need to be cloned, they come with the .emacs.d/ repo.
+// modeOverride replaces the cloneMode of every repo. "" uses each repo's cloneMode.
+func CloneYolo(ctx context.Context, env *Env, repos []config.GitRepo, modeOverride config.CloneMode) report.Report {
+	yoloFolder := env.expand(env.Settings.YoloRoot)
+	// the yoloRoot may be several folders deep in a fresh setup. ie ~/.emacs.d/notElpaYolo
+	if err := os.MkdirAll(yoloFolder, os.ModePerm); err != nil {
+		return report.Report{Summary: fmt.Sprintf("Failed to create folder %s, err: %v", yoloFolder, err)}
+	}
+	return cloneRunner(env, modeOverride).run(ctx, repos)
+}
+
+// CloneYoloEach clones like CloneYolo but sends the result of each repo as soon as it's done.
+func CloneYoloEach(ctx context.Context, env *Env, repos []config.GitRepo, modeOverride config.CloneMode) <-chan RepoResult {
+	return cloneRunner(env, modeOverride).each(ctx, repos)
+}
+
+func cloneRunner(env *Env, modeOverride config.CloneMode) runner {
+	return runner{
+		sections: []string{sectionCloned},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return cloneYolo(ctx, env, i, repo, modeOverride)
+		},
+		summary: func(t tally) string {
+			// print # of yolo repos checked, duration
+			return fmt.Sprintf("Checked for existence of %d yolo repos, clone if not exist. time elapsed: %v",
+				t.repos-t.skipped, t.elapsed)
+		},
+	}
+}
+
+// clone the "yolo" repo if it does not exist in target location.
+// modeOverride replaces the repo's cloneMode if not "".
+func cloneYolo(ctx context.Context, env *Env, i int, repo config.GitRepo, modeOverride config.CloneMode) RepoResult {
+	result := newResult(i, repo)
+
+	if !repo.IsYolo { // GUARD: for "yolo" repos only, not submodules
+		result.skip()
+		return result
+	}
+
+	folder := env.expand(repo.Folder)
+	folderExists, _ := exists(folder)
+	if folderExists {
+		// assume folder is the cloned repo. use the verify command to check it.
+		// return early early, nothing to clone
+		return result
+	}
+
+	// get default remote
+	remote, err := repo.RemoteDefault()
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+
+	mode := modeOverride
+	if mode == "" {
+		if mode, err = config.ParseCloneMode(repo.CloneMode); err != nil {
+			result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+			return result
+		}
+	}
+
+	// go to parent folder 1 level up to execute the clone command.
+	// because the target folder does not exist until after clone
+	if err := os.MkdirAll(parentDir(folder), os.ModePerm); err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+	cmd := gitops.Command(ctx, parentDir(folder), cloneArgs(mode, repo.BranchUse, remote.URL, folder)...)
+	stdout, err := gitops.CombinedOutput(cmd)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, err.Error()))
+		return result
+	}
+	// TODO: make sure there's nothing else i need to check for clone success/fail
+	result.add(sectionCloned, fmt.Sprintf("%d: %s %v %s\n",
+		i, repo.Folder, cmd.Args, string(stdout)))
+	return result
+}
+
+// fetch the full history of yolo repos that were shallow cloned.
+func Unshallow(ctx context.Context, env *Env, repos []config.GitRepo) report.Report {
+	return runner{
+		sections: []string{sectionUnshallowed},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return unshallow(ctx, env, i, repo)
+		},
+		summary: func(t tally) string {
+			// print # of yolo repos checked, duration
+			return fmt.Sprintf("Checked %d yolo repos for shallow clones. time elapsed: %v", t.repos-t.skipped, t.elapsed)
+		},
+	}.run(ctx, repos)
+}
+
+func unshallow(ctx context.Context, env *Env, i int, repo config.GitRepo) RepoResult {
+	result := newResult(i, repo)
+
+	if !repo.IsYolo {
+		result.skip()
+		return result
+	}
+
+	if folderExists, _ := exists(env.expand(repo.Folder)); !folderExists {
+		return result // not cloned yet. nothing to unshallow
+	}
+
+	// git rev-parse --is-shallow-repository
+	cmd := gitops.Command(ctx, env.expand(repo.Folder), "rev-parse", "--is-shallow-repository")
+	output, err := gitops.CombinedOutput(cmd)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, err.Error()))
+		return result
+	}
+	if strings.TrimSpace(string(output)) != "true" {
+		return result // full or blobless clone. already has the full history
+	}
+
+	remote, err := repo.RemoteDefault()
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+	// git fetch --unshallow origin
+	cmd = gitops.Command(ctx, env.expand(repo.Folder), "fetch", "--unshallow", remote.Alias)
+	output, err = gitops.CombinedOutput(cmd)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %v %s %s\n", i, repo.Folder, cmd.Args, err.Error(), output))
+		return result
+	}
+	result.add(sectionUnshallowed, fmt.Sprintf("%d: %s %v\n", i, repo.Folder, cmd.Args))
+	return result
+}
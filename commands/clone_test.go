@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func TestUnshallow(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	up := filepath.Join(dir, "up")
+	gitT(t, dir, "init", "-q", "-b", "master", up)
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "first")
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "second")
+	foo := filepath.Join(dir, "foo")
+	// --depth is ignored for local paths, use a file url.
+	gitT(t, dir, "clone", "-q", "--depth", "1", "file://"+up, foo)
+	if got := gitT(t, foo, "rev-parse", "--is-shallow-repository"); got != "true" {
+		t.Fatalf("clone is-shallow-repository %s, want true", got)
+	}
+
+	repo := config.GitRepo{
+		Name: "foo", Folder: foo, IsYolo: true,
+		Remotes:          []config.Remote{{Sym: "upstream", URL: "file://" + up, Alias: "origin"}},
+		RemoteDefaultSym: "upstream",
+	}
+	env := &Env{Home: home, Out: io.Discard}
+	rep := Unshallow(ctx, env, []config.GitRepo{repo})
+	for _, sec := range rep.Sections {
+		switch {
+		case sec.Title == sectionUnshallowed && (len(sec.Lines) != 1 || !strings.Contains(sec.Lines[0], "--unshallow")):
+			t.Errorf("%s section is %q, want foo unshallowed", sec.Title, sec.Lines)
+		case sec.Title == sectionFailures && len(sec.Lines) > 0:
+			t.Errorf("Unshallow() failed: %q", sec.Lines)
+		}
+	}
+	if got := gitT(t, foo, "rev-parse", "--is-shallow-repository"); got != "false" {
+		t.Errorf("is-shallow-repository %s after Unshallow, want false", got)
+	}
+	if got := gitT(t, foo, "rev-list", "--count", "HEAD"); got != "2" {
+		t.Errorf("%s commits after Unshallow, want the full history of 2", got)
+	}
+
+	// a full clone has nothing to do.
+	if got := unshallow(ctx, env, 0, repo); got.Outcome != Unchanged || len(got.Lines) != 0 {
+		t.Errorf("rerun got %v %q, want unchanged", got.Outcome, got.Lines)
+	}
+}
+
+func TestCloneYoloEach(t *testing.T) {
+	dir := t.TempDir()
+	up := filepath.Join(dir, "up")
+	gitT(t, dir, "init", "-q", "-b", "master", up)
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "init")
+
+	// the parent folders don't exist yet. each clone makes its own.
+	foo := filepath.Join(dir, "yolo", "deep", "foo")
+	repos := []config.GitRepo{
+		{
+			Name: "foo", Folder: foo, IsYolo: true, BranchUse: "master",
+			Remotes:          []config.Remote{{Sym: "upstream", URL: up, Alias: "origin"}},
+			RemoteDefaultSym: "upstream",
+		},
+		{Name: "sub", Folder: filepath.Join(dir, "sub")}, // a submodule. not cloned
+	}
+	env := &Env{Home: home, Out: io.Discard}
+	got := make(map[string]RepoResult, 2)
+	for res := range CloneYoloEach(context.Background(), env, repos, "") {
+		got[res.Repo.Name] = res
+	}
+	if res := got["foo"]; res.Outcome != Changed || !res.has(sectionCloned) {
+		t.Errorf("foo got %v %q, want cloned", res.Outcome, res.Lines)
+	}
+	if res := got["sub"]; res.Outcome != Skipped {
+		t.Errorf("sub got %v, want skipped", res.Outcome)
+	}
+	if isRepo, _ := exists(filepath.Join(foo, ".git")); !isRepo {
+		t.Errorf("%s is not a clone", foo)
+	}
+}
@@ -0,0 +1,310 @@
+package commands
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+)
+
+// the folder ~ expands to in the functional tests.
+var home string
+
+func TestMain(m *testing.M) {
+	// setup code here
+	home, _ = config.HomeDir("") // ~ path handling setup
+
+	exitCode := m.Run()
+
+	// teardown code here
+
+	os.Exit(exitCode)
+}
+
+func expandPath(path string) string {
+	return config.ExpandPath(path, home)
+}
+
+// functional test, not unit test.
+// may only work on my machine with folders setup.
+func TestExists(t *testing.T) {
+	// repos, but not submods
+	path1 := expandPath("~/.emacs.d")
+	path2 := expandPath("~/.emacs.d/notElpa")
+	// repo and is submod
+	// path3 := expandPath("~/.emacs.d/notElpa/magit")
+	// not a repo
+	path4 := expandPath("~/.vscode")
+	// is a "yolo" repo
+	path5 := expandPath("~/.emacs.d/notElpaYolo/binky.el")
+
+	want := true
+	got, _ := exists(path1)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = true
+	got, _ = exists(path2)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	// want = true
+	// got, _ = exists(path3)
+	// if got != want {
+	// 	t.Fatalf("got: %t. wanted %t", got, want)
+	// }
+
+	want = true
+	got, _ = exists(path4)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = true
+	got, _ = exists(path5)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = false
+	got, _ = exists("~/fake/path")
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+}
+
+func TestParentDir(t *testing.T) {
+	want := expandPath("~/.emacs.d/notElpaYolo")
+	got := parentDir(expandPath("~/.emacs.d/notElpaYolo/binky.el"))
+	if got != want {
+		t.Fatalf("got: %s. wanted %s", got, want)
+	}
+}
+
+func TestRemotesFor(t *testing.T) {
+	repo := config.GitRepo{
+		Name: "gitFetchHelper",
+		Remotes: []config.Remote{
+			{Sym: "mine", URL: "https://github.com/miketz/gitFetchHelper", Alias: "origin"},
+			{Sym: "upstream", URL: "https://github.com/miketz/gitFetchHelper", Alias: "origin"},
+			{Sym: "old", URL: "https://example.com/abandoned", Alias: "old"},
+		},
+		RemoteDefaultSym: "upstream",
+	}
+
+	// ad-hoc sym
+	got, err := RemotesFor(&repo, RemoteSym, "old")
+	if err != nil || len(got) != 1 || got[0].Alias != "old" {
+		t.Fatalf("got: %v, %v. wanted the old remote", got, err)
+	}
+
+	// missing ad-hoc sym is skipped quietly, not an error.
+	got, err = RemotesFor(&repo, RemoteSym, "nope")
+	if err != nil || len(got) != 0 {
+		t.Fatalf("got: %v, %v. wanted no remotes and no error", got, err)
+	}
+
+	// all remotes. "mine" and "upstream" share the origin alias so only fetch it once.
+	got, err = RemotesFor(&repo, RemoteAll, "")
+	if err != nil || len(got) != 2 || got[0].Alias != "origin" || got[1].Alias != "old" {
+		t.Fatalf("got: %v, %v. wanted origin and old", got, err)
+	}
+
+	// missing well known remote is still an error.
+	repo.Remotes = repo.Remotes[2:]
+	_, err = RemotesFor(&repo, RemoteUpstream, "")
+	if err == nil {
+		t.Fatalf("wanted an error for missing upstream remote")
+	}
+}
+
+func TestDiffBranch(t *testing.T) {
+	repo := config.GitRepo{BranchMain: "master", BranchUse: "mine"}
+	mine := config.Remote{Sym: "mine", Alias: "origin"}
+	upstream := config.Remote{Sym: "upstream", Alias: "upstream"}
+
+	if got := diffBranch(&repo, RemoteMine, &mine); got != "mine" {
+		t.Fatalf("got: %s. wanted mine", got)
+	}
+	if got := diffBranch(&repo, RemoteUpstream, &upstream); got != "master" {
+		t.Fatalf("got: %s. wanted master", got)
+	}
+	if got := diffBranch(&repo, RemoteDefault, &mine); got != "mine" {
+		t.Fatalf("got: %s. wanted mine", got)
+	}
+}
+
+func TestRefsUnchanged(t *testing.T) {
+	remoteRefs := gitops.LsRemoteRefs{Branches: map[string]string{"master": "a", "mine": "b"}}
+	tests := []struct {
+		local map[string]string
+		want  bool
+	}{
+		{map[string]string{"master": "a", "mine": "b"}, true},
+		{map[string]string{"master": "a"}, true},
+		{map[string]string{"master": "a", "mine": "old"}, false},
+		{map[string]string{"gone": "a"}, false}, // branch deleted on the remote
+	}
+	for _, tt := range tests {
+		if got := refsUnchanged(remoteRefs, tt.local); got != tt.want {
+			t.Errorf("refsUnchanged(%v) = %v, want %v", tt.local, got, tt.want)
+		}
+	}
+}
+
+func TestFetchTimesSaved(t *testing.T) {
+	times := fetchTimes{}
+	if _, ok := times.saved(); ok {
+		t.Errorf("saved() with nothing fetched should not be ok")
+	}
+	times.addFetch(2*time.Second, false)
+	times.addFetch(time.Minute, true) // a failed fetch is not a fetch time
+	times.addCheck(100*time.Millisecond, false)
+	times.addCheck(100*time.Millisecond, true)
+	times.addCheck(100*time.Millisecond, true)
+	// 2 skipped fetches of 2s, minus 300ms of checks.
+	if saved, ok := times.saved(); !ok || saved != 3700*time.Millisecond {
+		t.Errorf("saved() = %v %v, want 3.7s true", saved, ok)
+	}
+
+	// every remote unchanged. the fetch time of an earlier run is the estimate.
+	times = fetchTimes{baseline: time.Second}
+	times.addCheck(100*time.Millisecond, true)
+	if saved, ok := times.saved(); !ok || saved != 900*time.Millisecond {
+		t.Errorf("saved() = %v %v, want 900ms true from the baseline", saved, ok)
+	}
+	got := times.summary(tally{repos: 1, unchanged: 1})
+	if !strings.HasPrefix(got, "Fetched 0 of 1 remotes, skipped 1 unchanged") ||
+		!strings.Contains(got, "estimated time saved: 900ms") {
+		t.Errorf("summary() = %q, want nothing fetched and the time saved", got)
+	}
+}
+
+func TestFetchBaseline(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	if got := loadFetchBaseline(dir); got != 0 {
+		t.Errorf("loadFetchBaseline() = %v before any save, want 0", got)
+	}
+	times := fetchTimes{}
+	times.saveBaseline(dir) // nothing fetched, nothing saved
+	if got := loadFetchBaseline(dir); got != 0 {
+		t.Errorf("loadFetchBaseline() = %v after a run without fetches, want 0", got)
+	}
+	times.addFetch(time.Second, false)
+	times.addFetch(3*time.Second, false)
+	times.saveBaseline(dir)
+	if got := loadFetchBaseline(dir); got != 2*time.Second {
+		t.Errorf("loadFetchBaseline() = %v, want the 2s average", got)
+	}
+	if got := loadFetchBaseline(""); got != 0 {
+		t.Errorf("loadFetchBaseline(\"\") = %v, want 0", got)
+	}
+}
+
+func TestFetchBranches(t *testing.T) {
+	repo := config.GitRepo{
+		BranchMain: "master",
+		BranchUse:  "mine",
+		Remotes: []config.Remote{
+			{Sym: "upstream", Alias: "upstream"},
+			{Sym: "mine", Alias: "origin"},
+			{Sym: "backup", Alias: "origin"},
+		},
+	}
+	tests := []struct {
+		remoteType RemoteType
+		remote     config.Remote
+		want       string
+	}{
+		// the custom branch is never on the upstream.
+		{RemoteUpstream, repo.Remotes[0], "master"},
+		{RemoteAll, repo.Remotes[0], "master"},
+		// origin is shared by mine and an ad-hoc remote, which goes by BranchMain.
+		{RemoteMine, repo.Remotes[1], "mine master"},
+		{RemoteDefault, repo.Remotes[1], "mine master"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(fetchBranches(&repo, tt.remoteType, tt.remote), " "); got != tt.want {
+			t.Errorf("fetchBranches(%v, %s) = %s, want %s", tt.remoteType, tt.remote.Sym, got, tt.want)
+		}
+	}
+}
+
+func TestFetchTimesSavedSummary(t *testing.T) {
+	times := fetchTimes{}
+	times.addFetch(100*time.Millisecond, false)
+	times.addCheck(200*time.Millisecond, true)
+	times.addCheck(15*time.Millisecond, false)
+	// 1 skipped fetch of 100ms, minus 215ms of checks.
+	if got := times.savedSummary(); strings.Contains(got, "-") || !strings.Contains(got, "115ms") {
+		t.Errorf("savedSummary() = %q, want no negative time and the 115ms lost", got)
+	}
+	// less than 1ms either way rounds to nothing.
+	times = fetchTimes{baseline: 100 * time.Millisecond}
+	times.addCheck(100*time.Millisecond+300*time.Microsecond, true)
+	if got := times.savedSummary(); strings.Contains(got, "0s") {
+		t.Errorf("savedSummary() = %q, want no 0s time", got)
+	}
+}
+
+func TestCloneArgs(t *testing.T) {
+	tests := []struct {
+		mode config.CloneMode
+		want string
+	}{
+		{config.CloneFull, "clone --branch master URL /f/magit"},
+		{config.CloneShallow, "clone --depth 1 --no-single-branch --branch master URL /f/magit"},
+		{config.CloneBlobless, "clone --filter=blob:none --branch master URL /f/magit"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(cloneArgs(tt.mode, "master", "URL", "/f/magit"), " "); got != tt.want {
+			t.Errorf("cloneArgs(%s) = %s, want %s", tt.mode, got, tt.want)
+		}
+	}
+}
+
+// run git in folder for a test. Fails the test on error. Returns the trimmed output.
+func gitT(t *testing.T, folder string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = folder
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t",
+		"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t", "GIT_ALLOW_PROTOCOL=file")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v %v %s", cmd.Args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// write a file for a test, creating its folder.
+func writeFileT(t *testing.T, path, src string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// load the config of a test and get the repo named name.
+func loadRepoT(t *testing.T, path, name string) config.GitRepo {
+	t.Helper()
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, found := config.FindRepo(cfg.Repos, name)
+	if !found {
+		t.Fatalf("no repo %s in %s", name, path)
+	}
+	return repo
+}
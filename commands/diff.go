@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// title of the diff report section with the repos that differ from the remote.
+const sectionDiff = "NEW upstream code"
+
+// compare each repo's branch with the remote tracking branch of the remotes of
+// remoteType, as last fetched. Nothing is fetched, run Fetch first for the latest code.
+// The branch is BranchMain for upstream remotes and BranchUse for mine, see diffBranch.
+// The report lists the repos with a difference, not the diff itself, and the git
+// errors as failures. A repo is Changed if a remote differs, Unchanged if none does,
+// and Skipped if it has no remote of remoteType.
+// sym is only used when remoteType is RemoteSym.
+func Diff(ctx context.Context, env *Env, repos []config.GitRepo, remoteType RemoteType, sym string) report.Report {
+	return diffRunner(env, remoteType, sym).run(ctx, repos)
+}
+
+// DiffEach diffs like Diff but sends the result of each repo as soon as it's done.
+func DiffEach(ctx context.Context, env *Env, repos []config.GitRepo, remoteType RemoteType, sym string) <-chan RepoResult {
+	return diffRunner(env, remoteType, sym).each(ctx, repos)
+}
+
+func diffRunner(env *Env, remoteType RemoteType, sym string) runner {
+	return runner{
+		// only includes repos that have new data in upstream
+		sections: []string{sectionDiff},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return diff(ctx, env, i, repo, remoteType, sym)
+		},
+		summary: func(t tally) string { return t.remotes("Diffed") },
+	}
+}
+
+func diff(ctx context.Context, env *Env, i int, repo config.GitRepo, remoteType RemoteType, sym string) RepoResult {
+	result := newResult(i, repo)
+
+	// get current checked out branch name.
+	// It may be the configured repo.MainBranch, or custom "mine", or empty "" (detached head)
+	// branchName, err := gitops.CurrentBranch(ctx, env.expand(repo.Folder))
+	// if err != nil {
+	// 	result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, "problem getting current branch name: "+err.Error()))
+	// 	return result
+	// }
+
+	// get remote info
+	remotes, err := RemotesFor(&repo, remoteType, sym)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+	if len(remotes) == 0 {
+		result.skip()
+		return result
+	}
+
+	// each remote adds to the same line so the repo has at most 1 entry per report section.
+	for _, remote := range remotes {
+		branchName := diffBranch(&repo, remoteType, &remote)
+
+		// prepare diff command. example: git diff master upstream/master
+		// TODO: maybe compare git diff origin/master upstream/master
+		//       to handle case where i'm on a "mine" branch and "master" only exists as a remote-tracking branch after a clone
+		cmd := gitops.Command(ctx, env.expand(repo.Folder), "diff",
+			branchName,
+			// remote.Alias+"/"+repo.BranchMain)
+			remote.Alias+"/"+branchName)
+		// Run git diff!
+		stdout, err := gitops.CombinedOutput(cmd)
+		if err != nil {
+			result.fail(fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, err.Error()))
+			continue
+		}
+		hasDifference := len(stdout) > 0
+		if !hasDifference {
+			continue
+		}
+		// don't include the diff output in stdout as it's too verbose to display
+		result.add(sectionDiff, fmt.Sprintf("%d: %s %v\n",
+			i, repo.Folder, cmd.Args))
+	}
+	return result
+}
+
+// get the branch to compare against remote when diffing.
+// when comparing our current to upstream, we don't care about any custom changes in "mine" as those are expected difference from upstream.
+// instead compare repo.BranchMain if possible
+// or use HEAD if we are in a detached head state.
+// if we are comparieng against my remote fork or "default" remote then go ahead and use a non BranchMain in the
+// comparison
+func diffBranch(repo *config.GitRepo, remoteType RemoteType, remote *config.Remote) string {
+	// if branchName == "" {
+	// 	// detached head.
+	// 	branchName = "HEAD"
+	// }
+	switch remoteType {
+	case RemoteUpstream:
+		// BranchUse is possibly a custom branch. Don't compare that when dealing with upstream as my custom branch won't exist there.
+		return repo.BranchMain
+	case RemoteMine:
+		// BranchUse should always exist in my forked remote.
+		// in this case we are interested in syncing up with the latest .emacs.d/ and that means BranchUse
+		return repo.BranchUse
+	}
+	// RemoteDefault: in this case we are interested in syncing up with the latest .emacs.d/
+	// but some of the remotes may use the upstream directly (no personal fork), for those continue to compare against the offical main branch.
+	// RemoteSym, RemoteAll: same idea, go by the Sym of the remote.
+	isUpstreamRem := remote.Sym == "upstream"
+	isMineRem := !isUpstreamRem && remote.Sym == "mine"
+	if isUpstreamRem {
+		return repo.BranchMain
+	} else if isMineRem {
+		return repo.BranchUse
+	}
+	// ad-hoc remote. not mine, not the official upstream.
+	// maybe an old abondoned upstream remote configured in the json for informational purposes.
+	// this case should rarely occur
+	return repo.BranchMain
+}
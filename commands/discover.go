@@ -0,0 +1,321 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+)
+
+// Generate repos.jsonc entries for git repos that are on disk but not in the config yet.
+
+// find git repos under dir that are not in repos and build candidate GitRepo entries for them.
+// Only looks maxDepth folders deep and doesn't look inside repos it finds.
+func Discover(ctx context.Context, env *Env, repos []config.GitRepo, dir string, maxDepth int, user string) ([]config.GitRepo, []string, error) {
+	root := filepath.Clean(env.expand(dir))
+	known := knownFolders(env, repos)
+	candidates := make([]config.GitRepo, 0, 8)
+	problems := make([]string, 0, 2)
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// ie no permission. skip it, the rest of the folders are still worth a look.
+			problems = append(problems, fmt.Sprintf("%s %s\n", path, err.Error()))
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		depth := strings.Count(strings.TrimPrefix(path, root), string(filepath.Separator))
+		isRepo, _ := exists(filepath.Join(path, ".git"))
+		if !isRepo {
+			if depth >= maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if path == root {
+			return nil // dir itself is a repo (ie ~/.emacs.d). look for the clones inside it
+		}
+		if !known[path] {
+			repo, err := discoverRepo(ctx, env, path, user)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s %s\n", path, err.Error()))
+			} else {
+				candidates = append(candidates, repo)
+			}
+		}
+		return filepath.SkipDir // don't look for repos nested inside a repo
+	})
+	return candidates, problems, err
+}
+
+// absolute folders of every repo in repos.
+func knownFolders(env *Env, repos []config.GitRepo) map[string]bool {
+	known := make(map[string]bool, len(repos))
+	for i := 0; i < len(repos); i++ {
+		known[filepath.Clean(env.expand(repos[i].Folder))] = true
+	}
+	return known
+}
+
+// build a candidate GitRepo for the repo in folder from its remotes and current branch.
+func discoverRepo(ctx context.Context, env *Env, folder, user string) (config.GitRepo, error) {
+	remotes, err := gitops.Remotes(ctx, folder)
+	if err != nil {
+		return config.GitRepo{}, err
+	}
+	if len(remotes) == 0 {
+		return config.GitRepo{}, errors.New("no remotes")
+	}
+	branch, err := gitops.CurrentBranch(ctx, folder)
+	if err != nil {
+		return config.GitRepo{}, err
+	}
+	if branch == "" {
+		branch = "master" // detached head. a guess, fix by hand.
+	}
+	repo := config.GitRepo{
+		Name:       strings.TrimSuffix(filepath.Base(folder), ".el"),
+		Folder:     env.contract(folder),
+		Remotes:    guessSyms(remotes, user),
+		BranchMain: branch,
+		BranchUse:  branch,
+		IsYolo:     !gitops.IsInSubmodule(ctx, folder),
+	}
+	repo.RemoteDefaultSym = guessDefaultSym(repo.Remotes)
+	return repo, nil
+}
+
+// guess the Sym of each remote. A remote owned by user is "mine", the rest are "upstream".
+// If there are several of either kind the 1st keeps the Sym (preferring the alias
+// "upstream" for upstream) and the others use their alias as the Sym.
+func guessSyms(remotes []config.Remote, user string) []config.Remote {
+	guessed := make([]config.Remote, 0, len(remotes))
+	hasMine := false
+	upstreamIdx := -1
+	for i, rem := range remotes {
+		isMine := user != "" && strings.EqualFold(urlOwner(rem.URL), user)
+		switch {
+		case isMine && !hasMine:
+			rem.Sym = "mine"
+			hasMine = true
+		case !isMine && (upstreamIdx < 0 || rem.Alias == "upstream"):
+			if upstreamIdx >= 0 {
+				guessed[upstreamIdx].Sym = guessed[upstreamIdx].Alias // demote the earlier guess
+			}
+			rem.Sym = "upstream"
+			upstreamIdx = i
+		default:
+			rem.Sym = rem.Alias
+		}
+		guessed = append(guessed, rem)
+	}
+	return guessed
+}
+
+// the remote we track is the one we cloned, usually "origin".
+func guessDefaultSym(remotes []config.Remote) string {
+	for _, rem := range remotes {
+		if rem.Alias == "origin" {
+			return rem.Sym
+		}
+	}
+	for _, sym := range []string{"mine", "upstream"} {
+		for _, rem := range remotes {
+			if rem.Sym == sym {
+				return sym
+			}
+		}
+	}
+	return remotes[0].Sym
+}
+
+// get the owner (user or org) of a repo URL. ie "miketz" for
+// https://github.com/miketz/paredit or git@github.com:miketz/paredit.
+// Works for github style hosts where the owner is the 1st path segment.
+func urlOwner(url string) string {
+	if i := strings.Index(url, "://"); i >= 0 {
+		url = url[i+3:]
+		// drop the host
+		j := strings.Index(url, "/")
+		if j < 0 {
+			return ""
+		}
+		url = url[j+1:]
+	} else if i = strings.Index(url, ":"); i >= 0 {
+		url = url[i+1:] // scp style: git@github.com:miketz/paredit
+	} else {
+		return "" // local path
+	}
+	owner, _, found := strings.Cut(url, "/")
+	if !found {
+		return ""
+	}
+	return strings.TrimPrefix(owner, "~") // sourcehut: git.sr.ht/~owner/repo
+}
+
+// resolve a relative submodule URL (./foo, ../foo) against the superproject's URL.
+// Absolute URLs are returned as is.
+func resolveSubmoduleURL(superURL, url string) string {
+	if !strings.HasPrefix(url, "./") && !strings.HasPrefix(url, "../") {
+		return url
+	}
+	base := strings.TrimSuffix(strings.TrimRight(superURL, "/"), ".git")
+	for {
+		switch {
+		case strings.HasPrefix(url, "./"):
+			url = url[2:]
+		case strings.HasPrefix(url, "../"):
+			url = url[3:]
+			if i := strings.LastIndexAny(base, "/:"); i >= 0 {
+				base = base[:i+1]
+				base = strings.TrimRight(base, "/")
+				if strings.HasSuffix(base, ":") {
+					// scp style: git@github.com:owner
+					return base + url
+				}
+			}
+		default:
+			return base + "/" + url
+		}
+	}
+}
+
+// build candidate GitRepo entries (IsYolo false) for the submodules of superproject that
+// are not in repos yet. Entries are matched to repos by Folder.
+func ImportSubmodules(ctx context.Context, env *Env, repos []config.GitRepo, superproject, user string) ([]config.GitRepo, []string, error) {
+	subs, err := gitops.ReadGitmodules(ctx, env.expand(superproject))
+	if err != nil {
+		return nil, nil, err
+	}
+	superFolder := filepath.Clean(env.expand(superproject))
+	superURL := ""
+	if remotes, err := gitops.Remotes(ctx, superFolder); err == nil { //nolint:govet
+		for _, rem := range remotes {
+			if rem.Alias == "origin" {
+				superURL = rem.URL
+			}
+		}
+	}
+
+	known := knownFolders(env, repos)
+	candidates := make([]config.GitRepo, 0, len(subs))
+	problems := make([]string, 0, 2)
+	alreadyConfigured := 0
+	for _, sub := range subs {
+		if sub.Path == "" || sub.URL == "" {
+			problems = append(problems, fmt.Sprintf("%s submodule %s is missing a path or url\n", superproject, sub.Name))
+			continue
+		}
+		folder := filepath.Join(superFolder, filepath.FromSlash(sub.Path))
+		if known[folder] {
+			alreadyConfigured++
+			continue
+		}
+		repo := config.GitRepo{
+			Name:   strings.TrimSuffix(filepath.Base(folder), ".el"),
+			Folder: env.contract(folder),
+			IsYolo: false,
+		}
+		remotes := []config.Remote{{Alias: "origin", URL: resolveSubmoduleURL(superURL, sub.URL)}}
+		branch := sub.Branch
+		// a checked out submodule may know more. ie an extra upstream remote
+		if isCheckedOut, _ := exists(filepath.Join(folder, ".git")); isCheckedOut {
+			if actual, err := gitops.Remotes(ctx, folder); err == nil { //nolint:govet
+				for _, rem := range actual {
+					if rem.Alias != "origin" {
+						remotes = append(remotes, rem)
+					}
+				}
+			}
+			if branch == "" {
+				branch, _ = gitops.CurrentBranch(ctx, folder)
+			}
+		}
+		if branch == "" || branch == "." {
+			branch = "master" // "." means same name as the superproject branch. a guess, fix by hand.
+		}
+		repo.Remotes = guessSyms(remotes, user)
+		repo.RemoteDefaultSym = guessDefaultSym(repo.Remotes)
+		repo.BranchMain = branch
+		repo.BranchUse = branch
+		candidates = append(candidates, repo)
+	}
+	fmt.Fprintf(env.Out, "Submodules in %s: %d. already in %s: %d\n\n", superproject, len(subs), env.ConfigPath, alreadyConfigured)
+	return candidates, problems, nil
+}
+
+// get the user that owns my forks. from the git config github.user, otherwise the most
+// common owner of the "mine" remotes already in repos.jsonc.
+func DefaultForkUser(ctx context.Context, repos []config.GitRepo) string {
+	if user, err := gitops.Output(ctx, "", "config", "--get", "github.user"); err == nil && user != "" {
+		return user
+	}
+	counts := make(map[string]int)
+	best := ""
+	for i := 0; i < len(repos); i++ {
+		mine, err := repos[i].RemoteMine()
+		if err != nil {
+			continue
+		}
+		owner := urlOwner(mine.URL)
+		counts[owner]++
+		if owner != "" && counts[owner] > counts[best] {
+			best = owner
+		}
+	}
+	return best
+}
+
+// print candidate entries, or with merge add them to repos.jsonc. Candidates whose name
+// is already used are reported and skipped.
+func PrintOrMergeCandidates(env *Env, repos []config.GitRepo, candidates []config.GitRepo, problems []string, merge bool) error {
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].Name < candidates[b].Name })
+	usedNames := make(map[string]bool, len(repos))
+	for i := 0; i < len(repos); i++ {
+		usedNames[repos[i].Name] = true
+	}
+
+	added := 0
+	for i := range candidates {
+		repo := &candidates[i]
+		if usedNames[repo.Name] {
+			problems = append(problems, fmt.Sprintf("%s name %s is already used. add it by hand with a different name\n", repo.Folder, repo.Name))
+			continue
+		}
+		usedNames[repo.Name] = true
+		if !merge {
+			fmt.Fprintf(env.Out, " %s,\n", config.FormatRepoEntry(repo))
+			continue
+		}
+		if err := config.AddRepo(env.ConfigPath, repo); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %s\n", repo.Folder, err.Error()))
+			continue
+		}
+		added++
+	}
+
+	if merge {
+		fmt.Fprintf(env.Out, "\nAdded %d of %d candidate repos to %s\n", added, len(candidates), env.ConfigPath)
+	} else {
+		fmt.Fprintf(env.Out, "\nCandidate repos not in %s: %d. use --merge to add them\n", env.ConfigPath, len(candidates))
+	}
+	fmt.Fprintf(env.Out, "\nPROBLEMS: %d\n", len(problems))
+	for _, p := range problems {
+		fmt.Fprint(env.Out, p)
+	}
+	return nil
+}
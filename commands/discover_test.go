@@ -0,0 +1,114 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func TestUrlOwner(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/miketz/paredit":     "miketz",
+		"git@github.com:miketz/paredit.git":     "miketz",
+		"ssh://git@gitlab.com/someone/repo":     "someone",
+		"https://git.sr.ht/~technomancy/fennel": "technomancy",
+		"https://paredit.org/paredit.git":       "",
+		"/home/mike/src/local":                  "",
+	}
+	for url, want := range tests {
+		if got := urlOwner(url); got != want {
+			t.Fatalf("%s got: %s. wanted %s", url, got, want)
+		}
+	}
+}
+
+func TestGuessSyms(t *testing.T) {
+	remotes := []config.Remote{
+		{Alias: "origin", URL: "https://github.com/miketz/swiper"},
+		{Alias: "old", URL: "https://github.com/someoneElse/swiper"},
+		{Alias: "upstream", URL: "https://github.com/abo-abo/swiper"},
+	}
+	got := guessSyms(remotes, "MikeTZ")
+	want := []string{"mine", "old", "upstream"}
+	for i := range want {
+		if got[i].Sym != want[i] {
+			t.Fatalf("got: %v. wanted syms %v", got, want)
+		}
+	}
+	if sym := guessDefaultSym(got); sym != "mine" {
+		t.Fatalf("got: %s. wanted the origin remote's sym", sym)
+	}
+
+	// no fork user known. everything is upstream-ish.
+	got = guessSyms(remotes[:1], "")
+	if got[0].Sym != "upstream" {
+		t.Fatalf("got: %v. wanted upstream", got)
+	}
+}
+
+func TestResolveSubmoduleURL(t *testing.T) {
+	tests := []struct {
+		superURL, url, want string
+	}{
+		{"https://github.com/miketz/.emacs.d.git", "../magit", "https://github.com/miketz/magit"},
+		{"https://github.com/miketz/.emacs.d", "./sub", "https://github.com/miketz/.emacs.d/sub"},
+		{"git@github.com:miketz/.emacs.d.git", "../../magit/magit", "git@github.com:magit/magit"},
+		{"https://github.com/miketz/.emacs.d", "https://github.com/x/y", "https://github.com/x/y"},
+	}
+	for _, tt := range tests {
+		if got := resolveSubmoduleURL(tt.superURL, tt.url); got != tt.want {
+			t.Fatalf("%s %s got: %s. wanted %s", tt.superURL, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoverUnreadableFolder(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root reads folders without permission")
+	}
+	dir := t.TempDir()
+	up := filepath.Join(dir, "up")
+	gitT(t, dir, "init", "-q", "-b", "master", up)
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "init")
+	gitT(t, dir, "clone", "-q", up, filepath.Join(dir, "root", "found"))
+	locked := filepath.Join(dir, "root", "locked")
+	if err := os.Mkdir(locked, 0o000); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chmod(locked, 0o755) })
+
+	env := &Env{Home: home, Out: io.Discard}
+	candidates, problems, err := Discover(context.Background(), env, nil, filepath.Join(dir, "root"), 2, "")
+	if err != nil {
+		t.Fatalf("Discover() err %v, want the scan to go on", err)
+	}
+	if len(candidates) != 1 || candidates[0].Name != "found" {
+		t.Errorf("candidates %+v, want found", candidates)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], locked) {
+		t.Errorf("problems %q, want 1 for %s", problems, locked)
+	}
+}
+
+func TestDefaultForkUser(t *testing.T) {
+	gitConfig := filepath.Join(t.TempDir(), "gitconfig")
+	t.Setenv("GIT_CONFIG_GLOBAL", gitConfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	repos := []config.GitRepo{
+		{Remotes: []config.Remote{{Sym: "mine", URL: "https://github.com/me/a"}}},
+		{Remotes: []config.Remote{{Sym: "mine", URL: "https://github.com/me/b"}}},
+		{Remotes: []config.Remote{{Sym: "mine", URL: "https://github.com/other/c"}}},
+	}
+	if got := DefaultForkUser(context.Background(), repos); got != "me" {
+		t.Errorf("without github.user got %q, want the most common owner me", got)
+	}
+	writeFileT(t, gitConfig, "[github]\n\tuser = configured\n")
+	if got := DefaultForkUser(context.Background(), repos); got != "configured" {
+		t.Errorf("got %q, want github.user configured", got)
+	}
+}
@@ -0,0 +1,80 @@
+// Package commands runs the gitFetchHelper operations (fetch, diff, mergeMine, ...) over
+// a set of repos. Each operation returns a report instead of printing it, so a program
+// can run them on any repos it likes and render the results its own way.
+package commands
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitFetchHelper/config"
+)
+
+// new line character.
+var newLine = "\n"
+
+// Env is what the operations need to know about this machine besides the repos.
+type Env struct {
+	// the settings resolved for this machine. see config.Settings.ForOS
+	Settings config.Settings
+	// the folder ~ expands to. see config.HomeDir
+	Home string
+	// the config file new repos are added to. ie ./repos.jsonc
+	ConfigPath string
+	// where add, remove, migrateToYolo and discover print their progress. The other
+	// operations return a report instead.
+	Out io.Writer
+	// where fetch keeps its timings between runs. ie ~/.cache/gitFetchHelper. Nothing
+	// is kept if empty.
+	CacheDir string
+}
+
+// build the Env for settings resolved by config.Settings.ForOS. Progress goes to stdout.
+func NewEnv(settings config.Settings, configPath string) (*Env, error) {
+	home, err := config.HomeDir(settings.Home)
+	if err != nil {
+		return nil, err
+	}
+	env := &Env{Settings: settings, Home: home, ConfigPath: configPath, Out: os.Stdout}
+	if cache, err := os.UserCacheDir(); err == nil {
+		env.CacheDir = filepath.Join(cache, "gitFetchHelper")
+	}
+	return env, nil
+}
+
+// expand environment variables and ~ in path.
+func (e *Env) expand(path string) string {
+	return config.ExpandPath(path, e.Home)
+}
+
+// where folders moved out of the yoloRoot go by default. ie archived by remove or
+// broken ones moved aside by verify. <yoloRoot>Archive so discover doesn't find them.
+func (e *Env) DefaultArchiveDir() string {
+	return strings.TrimRight(e.Settings.YoloRoot, "/") + "Archive"
+}
+
+// replace the home dir prefix of path with "~" so the config works on other machines.
+func (e *Env) contract(path string) string {
+	return config.ContractPath(path, e.Home)
+}
+
+// returns true if file or directory exists.
+func exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// get a path string to the parent dir of path.
+// string manipulation, directories do not need to exist on disk.
+func parentDir(path string) string {
+	parentDir := filepath.Join(path, "../")
+	return parentDir
+}
@@ -0,0 +1,275 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+	"golang.org/x/exp/slices"
+)
+
+// FetchOptions are the choices of Fetch.
+type FetchOptions struct {
+	// the remotes fetched.
+	Remote RemoteType
+	// Sym of the remote fetched. only used when Remote is RemoteSym.
+	Sym string
+	// check the remote's branch with git ls-remote first and skip the fetch if it's the
+	// same as the local tracking branch. BranchMain on the upstream, BranchUse on mine.
+	SkipUnchanged bool
+}
+
+// timing of fetches and ls-remote checks. used to estimate the time saved by skipping
+// fetches of unchanged remotes.
+type fetchTimes struct {
+	mut       sync.Mutex
+	fetched   int           // # of git fetch that worked
+	failed    int           // # of git fetch that failed
+	fetchTime time.Duration // total time of the git fetch that worked
+	checked   int           // # of ls-remote checks
+	checkTime time.Duration // total time of ls-remote checks
+	skipped   int           // # of fetches skipped as the remote was unchanged
+	// average time of 1 fetch in an earlier run. the estimate when this run fetched nothing.
+	baseline time.Duration
+}
+
+func (t *fetchTimes) addFetch(d time.Duration, failed bool) {
+	t.mut.Lock()
+	if failed {
+		t.failed++ // a failure is often a quick error or a timeout. not a fetch time
+	} else {
+		t.fetched++
+		t.fetchTime += d
+	}
+	t.mut.Unlock()
+}
+
+func (t *fetchTimes) addCheck(d time.Duration, unchanged bool) {
+	t.mut.Lock()
+	t.checked++
+	t.checkTime += d
+	if unchanged {
+		t.skipped++
+	}
+	t.mut.Unlock()
+}
+
+// average time of 1 fetch. This run's if it fetched, else the baseline of an earlier run.
+// 0 if neither is known.
+func (t *fetchTimes) avgFetch() time.Duration {
+	if t.fetched > 0 {
+		return t.fetchTime / time.Duration(t.fetched)
+	}
+	return t.baseline
+}
+
+// estimated time saved by the skipped fetches, minus the time spent on the ls-remote
+// checks. false if no fetch time is known to compare with.
+func (t *fetchTimes) saved() (time.Duration, bool) {
+	avgFetch := t.avgFetch()
+	if avgFetch == 0 || t.checked == 0 {
+		return 0, false
+	}
+	// every check costs time, even the ones followed by a fetch.
+	return time.Duration(t.skipped)*avgFetch - t.checkTime, true
+}
+
+// the estimated time saved for the report summary. The checks may cost more than the
+// skipped fetches, ie when most remotes changed. that's said instead of a negative time.
+func (t *fetchTimes) savedSummary() string {
+	saved, ok := t.saved()
+	if !ok {
+		return "no fetch time known yet to estimate the time saved."
+	}
+	saved = saved.Round(time.Millisecond)
+	switch {
+	case saved > 0:
+		return fmt.Sprintf("estimated time saved: %v", saved)
+	case saved < 0:
+		return fmt.Sprintf("no time saved, the checks took %v more than the skipped fetches.", -saved)
+	}
+	return "no time saved, the checks took as long as the skipped fetches."
+}
+
+// the report summary of a fetch with ls-remote checks. Skipped remotes are not counted
+// as fetched.
+func (t *fetchTimes) summary(tl tally) string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Fetched %d of %d remotes, skipped %d unchanged (checked with ls-remote).",
+		t.fetched, t.checked, t.skipped)
+	if t.failed > 0 {
+		fmt.Fprintf(&summary, " %d failed.", t.failed)
+	}
+	fmt.Fprintf(&summary, " time elapsed: %v\n%s", tl.elapsed, t.savedSummary())
+	if tl.skipped > 0 {
+		fmt.Fprintf(&summary, "\nSkipped %d repos without a matching remote.", tl.skipped)
+	}
+	return summary.String()
+}
+
+// file in the cache dir with the average fetch time of the last run that fetched.
+const fetchBaselineFile = "fetch-baseline"
+
+// read the average fetch time saved by an earlier run. 0 if there is none.
+func loadFetchBaseline(cacheDir string) time.Duration {
+	if cacheDir == "" {
+		return 0
+	}
+	src, err := os.ReadFile(filepath.Join(cacheDir, fetchBaselineFile))
+	if err != nil {
+		return 0
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(string(src)))
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// save this run's average fetch time for the estimates of later runs. Nothing is saved
+// if nothing was fetched.
+func (t *fetchTimes) saveBaseline(cacheDir string) {
+	if cacheDir == "" || t.fetched == 0 {
+		return
+	}
+	if err := os.MkdirAll(cacheDir, os.ModePerm); err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(cacheDir, fetchBaselineFile), []byte(t.avgFetch().String()+"\n"), 0o644) // only an estimate. nothing to do about an error
+}
+
+// title of the fetch report section with the output of fetches that pulled new data.
+const sectionFetched = "NEW repo data fetched"
+
+// Fetch from remote for each repo, measure time, report. The main flow.
+func Fetch(ctx context.Context, env *Env, repos []config.GitRepo, opts FetchOptions) report.Report {
+	return fetchRunner(env, opts).run(ctx, repos)
+}
+
+// FetchEach fetches like Fetch but sends the result of each repo as soon as it's done
+// instead of a report at the end. The channel is closed after the last repo.
+func FetchEach(ctx context.Context, env *Env, repos []config.GitRepo, opts FetchOptions) <-chan RepoResult {
+	return fetchRunner(env, opts).each(ctx, repos)
+}
+
+func fetchRunner(env *Env, opts FetchOptions) runner {
+	times := &fetchTimes{}
+	if opts.SkipUnchanged {
+		times.baseline = loadFetchBaseline(env.CacheDir)
+	}
+	return runner{
+		// only includes repos that had new data to fetch.
+		sections: []string{sectionFetched},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return fetch(ctx, env, i, repo, opts, times)
+		},
+		summary: func(t tally) string {
+			if !opts.SkipUnchanged {
+				// print # of remotes fetched, duration
+				return t.remotes("Fetched")
+			}
+			// the summary is made once the run is over. keep its timing for the next run.
+			times.saveBaseline(env.CacheDir)
+			return times.summary(t)
+		},
+	}
+}
+
+// Fetch remote for repo. i is the position of the repo, shown in the report.
+// Fetches several remotes when opts.Remote is RemoteAll.
+func fetch(ctx context.Context, env *Env, i int, repo config.GitRepo, opts FetchOptions, times *fetchTimes) RepoResult {
+	result := newResult(i, repo)
+
+	// get remote info
+	remotes, err := RemotesFor(&repo, opts.Remote, opts.Sym)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+	if len(remotes) == 0 {
+		result.skip()
+		return result
+	}
+
+	// each remote adds to the same line so the repo has at most 1 entry per report section.
+	for _, remote := range remotes {
+		if opts.SkipUnchanged {
+			checkStart := time.Now()
+			unchanged := remoteUnchanged(ctx, env, &repo, remote, fetchBranches(&repo, opts.Remote, remote))
+			times.addCheck(time.Since(checkStart), unchanged)
+			if unchanged {
+				continue
+			}
+		}
+		// prepare fetch command. example: git fetch upstream
+		cmd := gitops.Command(ctx, env.expand(repo.Folder), "fetch", remote.Alias)
+		// Run git fetch! NOTE: cmd.Output() doesn't include the output when git fetch pulls new data.
+		fetchStart := time.Now()
+		stdout, err := gitops.CombinedOutput(cmd)
+		times.addFetch(time.Since(fetchStart), err != nil)
+		if err != nil {
+			result.fail(fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, err.Error()))
+			continue
+		}
+		newDataFetched := len(stdout) > 0
+		if !newDataFetched {
+			continue
+		}
+		result.add(sectionFetched, fmt.Sprintf("%d: %s %v %s\n",
+			i, repo.Folder, cmd.Args, string(stdout)))
+	}
+	return result
+}
+
+// the branches of repo that remote is checked for before a fetch. Picked like the diff
+// does: BranchMain on the upstream, BranchUse on mine. A remote whose alias is shared,
+// ie "mine" and "upstream" both "origin", is checked for the branches of each.
+func fetchBranches(repo *config.GitRepo, remoteType RemoteType, remote config.Remote) []string {
+	branches := []string{diffBranch(repo, remoteType, &remote)}
+	for _, rem := range repo.Remotes {
+		if rem.Alias != remote.Alias {
+			continue
+		}
+		if branch := diffBranch(repo, RemoteSym, &rem); !slices.Contains(branches, branch) {
+			branches = append(branches, branch)
+		}
+	}
+	return branches
+}
+
+// true if branches on remote are at the same commits as the local remote tracking
+// branches, so a fetch would bring nothing new for them. Any error counts as changed so
+// the fetch runs as normal and reports it.
+func remoteUnchanged(ctx context.Context, env *Env, repo *config.GitRepo, remote config.Remote, branches []string) bool {
+	dir := env.expand(repo.Folder)
+	remoteRefs, err := gitops.LsRemote(ctx, dir, remote.Alias)
+	if err != nil {
+		return false
+	}
+	local := make(map[string]string, len(branches))
+	for _, branch := range branches {
+		hash, err := gitops.Hash(ctx, dir, "refs/remotes/"+remote.Alias+"/"+branch)
+		if err != nil {
+			return false // never fetched
+		}
+		local[branch] = hash
+	}
+	return refsUnchanged(remoteRefs, local)
+}
+
+// true if every branch in local has the same hash on the remote.
+func refsUnchanged(remoteRefs gitops.LsRemoteRefs, local map[string]string) bool {
+	for branch, hash := range local {
+		if remoteRefs.Branches[branch] != hash {
+			return false
+		}
+	}
+	return true
+}
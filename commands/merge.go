@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// title of the merge report section with the repos that merged new commits.
+const sectionMerged = "Repos merged"
+
+// merge in the code form "mine" remotes for BranchUse. the "mine" remotes are my forks
+// or personal projects so it's OK for them to be merged without review.
+// The Sym of the remote merged is the MergeSym setting, "mine" by default.
+func MergeMine(ctx context.Context, env *Env, repos []config.GitRepo) report.Report {
+	return mergeRunner(env).run(ctx, repos)
+}
+
+// MergeMineEach merges like MergeMine but sends the result of each repo as soon as it's done.
+func MergeMineEach(ctx context.Context, env *Env, repos []config.GitRepo) <-chan RepoResult {
+	return mergeRunner(env).each(ctx, repos)
+}
+
+func mergeRunner(env *Env) runner {
+	return runner{
+		// only includes repos that had new data to merge.
+		sections: []string{sectionMerged},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return merge(ctx, env, i, repo)
+		},
+		summary: func(t tally) string {
+			// print # of remotes merged, duration
+			return fmt.Sprintf("Merged %d of %d remotes. time elapsed: %v", t.changed, t.repos, t.elapsed)
+		},
+	}
+}
+
+func merge(ctx context.Context, env *Env, i int, repo config.GitRepo) RepoResult {
+	result := newResult(i, repo)
+
+	remoteMine, err := repo.GetRemoteBySym(env.Settings.MergeSym)
+	// this err just means no "mine" remote was configured in the
+	// jsonc. so don't add to the failures, just skip. TODO: make it return a bool, not err
+	hasRemoteMine := err == nil
+	if !hasRemoteMine {
+		result.skip()
+		return result
+	}
+	currBranch, err := gitops.CurrentBranch(ctx, env.expand(repo.Folder))
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, "problem getting current branch name: "+err.Error()))
+		return result
+	}
+	// verify BranchUse is checked out. don't switch to BranchUse as there may be
+	// unstaged changes. just fail.
+	if currBranch != repo.BranchUse {
+		result.fail(fmt.Sprintf("%d: %s %s must be checked out before a merging from my remote.\n", i, repo.Folder, repo.BranchUse))
+		return result
+	}
+
+	// git merge origin/master
+	cmd := gitops.Command(ctx, env.expand(repo.Folder), "merge", remoteMine.Alias+"/"+repo.BranchUse)
+	// Run branch switch!
+	stdout, err := gitops.CombinedOutput(cmd)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, err.Error()))
+		return result
+	}
+	// Merge and checking output is faster than checking hashes of master, origin/master in benchmarks.
+	// At least with slow shelling out commands.
+	output := string(stdout)
+	if output == "Already up to date.\n" { // NOTE: this logic will break if msg changes in future
+		return result // nothing to merge, don't add to success report
+	}
+	lines := strings.Split(output, newLine)
+	line2 := lines[1]
+	// TODO: find a better way of detecting error or conflict. They could change the
+	// message break this code.
+	mergeFailed := strings.HasPrefix(line2, "error") || strings.HasPrefix(line2, "CONFLICT")
+	if mergeFailed {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, output))
+		return result
+	}
+	// successful merge
+	result.add(sectionMerged, fmt.Sprintf("%d: %s %v %s\n",
+		i, repo.Folder, cmd.Args, output))
+	return result
+}
@@ -0,0 +1,230 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+)
+
+// Convert a notElpa git submodule into a normal clone in the ignored notElpaYolo folder.
+
+// 1 step of a migration. Steps run in order and stop at the 1st failure.
+type migrateStep struct {
+	desc string
+	run  func() error
+}
+
+// migrate the submodule repo to a yolo clone in yoloFolder (default
+// <yoloRoot>/<folder name>). With dryRun the steps are only printed.
+// force skips the uncommitted changes/unpushed commits check.
+func MigrateToYolo(ctx context.Context, env *Env, repo config.GitRepo, yoloFolder string, dryRun, force bool) error {
+	steps, err := planMigrateToYolo(ctx, env, &repo, yoloFolder, force)
+	if err != nil {
+		return err
+	}
+	for n, step := range steps {
+		fmt.Fprintf(env.Out, "%d: %s\n", n+1, step.desc)
+		if dryRun {
+			continue
+		}
+		if err = step.run(); err != nil {
+			return fmt.Errorf("step %d failed, later steps not run: %w", n+1, err)
+		}
+	}
+	if dryRun {
+		fmt.Fprintf(env.Out, "\nDry run. nothing changed.\n")
+		return nil
+	}
+	fmt.Fprintf(env.Out, "\nMigrated %s. commit the submodule removal in the superproject.\n", repo.Name)
+	return nil
+}
+
+// check the submodule can be migrated and build the steps to do it.
+func planMigrateToYolo(ctx context.Context, env *Env, repo *config.GitRepo, yoloFolder string, force bool) ([]migrateStep, error) {
+	if repo.IsYolo {
+		return nil, fmt.Errorf("%s is already a yolo repo", repo.Name)
+	}
+	folder := filepath.Clean(env.expand(repo.Folder))
+	if !gitops.IsInSubmodule(ctx, folder) {
+		return nil, fmt.Errorf("%s is not a checked out git submodule", repo.Folder)
+	}
+	superproject, err := gitops.Output(ctx, folder, "rev-parse", "--show-superproject-working-tree")
+	if err != nil {
+		return nil, err
+	}
+	subPath, err := filepath.Rel(superproject, folder)
+	if err != nil {
+		return nil, err
+	}
+	subPath = filepath.ToSlash(subPath)
+	subName, err := submoduleName(ctx, superproject, subPath)
+	if err != nil {
+		return nil, err
+	}
+	superGitDir, err := gitops.Output(ctx, superproject, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, err
+	}
+
+	if !force {
+		status, err := gitops.UncommittedChanges(ctx, folder)
+		if err != nil {
+			return nil, err
+		}
+		unpushed, err := gitops.UnpushedCommits(ctx, folder)
+		if err != nil {
+			return nil, err
+		}
+		if status != "" || unpushed != "" {
+			return nil, fmt.Errorf("%s has uncommitted changes or unpushed commits. use --force to migrate anyway.\n%s%s",
+				repo.Folder, status, unpushed)
+		}
+	}
+
+	// where the submodule is now. the clone is put at the same commit.
+	commit, err := gitops.Hash(ctx, folder, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	branch, err := gitops.CurrentBranch(ctx, folder)
+	if err != nil {
+		return nil, err
+	}
+	cloneBranch := branch
+	if cloneBranch == "" {
+		cloneBranch = repo.BranchUse // detached head
+	}
+
+	remote, err := repo.RemoteDefault()
+	if err != nil {
+		return nil, err
+	}
+	if yoloFolder == "" {
+		yoloFolder = filepath.Join(env.expand(env.Settings.YoloRoot), filepath.Base(folder))
+	}
+	yoloFolder = filepath.Clean(env.expand(yoloFolder))
+	if yoloExists, _ := exists(yoloFolder); yoloExists {
+		return nil, fmt.Errorf("%s already exists", yoloFolder)
+	}
+
+	// a field set in an override is changed there, changing Source would do nothing.
+	paths, byFile, err := fieldsByFile(repo, map[string]string{
+		"folder": config.JSONString(env.contract(yoloFolder)),
+		"isYolo": "true",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	steps := make([]migrateStep, 0, 8)
+	steps = append(steps, migrateStep{
+		desc: fmt.Sprintf("clone %s branch %s into %s", remote.URL, cloneBranch, yoloFolder),
+		run: func() error {
+			if err := os.MkdirAll(parentDir(yoloFolder), os.ModePerm); err != nil {
+				return err
+			}
+			// git clone --branch master url folder
+			_, err := gitops.Output(ctx, parentDir(yoloFolder), "clone", "--origin", remote.Alias, "--branch", cloneBranch, remote.URL, yoloFolder)
+			return err
+		},
+	})
+	if branch != "" {
+		steps = append(steps, migrateStep{
+			desc: fmt.Sprintf("reset %s to the submodule's commit %s", branch, commit),
+			run: func() error {
+				_, err := gitops.Output(ctx, yoloFolder, "reset", "--hard", commit)
+				return err
+			},
+		})
+	} else {
+		steps = append(steps, migrateStep{
+			desc: fmt.Sprintf("checkout the submodule's detached commit %s", commit),
+			run: func() error {
+				_, err := gitops.Output(ctx, yoloFolder, "checkout", "--detach", commit)
+				return err
+			},
+		})
+	}
+	for _, rem := range repo.Remotes {
+		if rem.Alias == remote.Alias || isDuplicateAlias(repo.Remotes, rem) {
+			continue
+		}
+		steps = append(steps, migrateStep{
+			desc: fmt.Sprintf("add remote %s %s", rem.Alias, rem.URL),
+			run: func() error {
+				_, err := gitops.Output(ctx, yoloFolder, "remote", "add", rem.Alias, rem.URL)
+				return err
+			},
+		})
+	}
+	steps = append(steps,
+		migrateStep{
+			desc: fmt.Sprintf("remove submodule %s from %s", subPath, superproject),
+			run: func() error {
+				if _, err := gitops.Output(ctx, superproject, "submodule", "deinit", "-f", "--", subPath); err != nil {
+					return err
+				}
+				if _, err := gitops.Output(ctx, superproject, "rm", "-f", "--", subPath); err != nil {
+					return err
+				}
+				// the submodule's git dir is left behind by git rm
+				return os.RemoveAll(filepath.Join(superGitDir, "modules", filepath.FromSlash(subName)))
+			},
+		},
+		migrateStep{
+			desc: fmt.Sprintf("set folder to %s and isYolo to true in %s", env.contract(yoloFolder), strings.Join(paths, ", ")),
+			run: func() error {
+				for _, path := range paths {
+					if err := config.SetRepoFields(path, repo.Name, byFile[path]); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	)
+
+	if relYolo, err := filepath.Rel(superproject, yoloFolder); err == nil && !strings.HasPrefix(relYolo, "..") {
+		relYolo = filepath.ToSlash(relYolo)
+		steps = append(steps, migrateStep{
+			desc: fmt.Sprintf("ignore %s in %s/.gitignore if not ignored already", relYolo, superproject),
+			run: func() error {
+				return gitops.Ignore(ctx, superproject, relYolo)
+			},
+		})
+	}
+	return steps, nil
+}
+
+// true if an earlier remote in remotes has the same alias as rem. ie "mine" and
+// "upstream" both being "origin" for my own projects.
+func isDuplicateAlias(remotes []config.Remote, rem config.Remote) bool {
+	for _, r := range remotes {
+		if r == rem {
+			return false
+		}
+		if r.Alias == rem.Alias {
+			return true
+		}
+	}
+	return false
+}
+
+// get the name of the submodule at subPath from the superproject's .gitmodules.
+func submoduleName(ctx context.Context, superproject, subPath string) (string, error) {
+	subs, err := gitops.ReadGitmodules(ctx, superproject)
+	if err != nil {
+		return "", err
+	}
+	for _, sub := range subs {
+		if sub.Path == subPath {
+			return sub.Name, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not in %s/.gitmodules", subPath, superproject)
+}
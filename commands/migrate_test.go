@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func TestIsDuplicateAlias(t *testing.T) {
+	remotes := []config.Remote{
+		{Sym: "mine", URL: "git@github.com:me/proj.git", Alias: "origin"},
+		{Sym: "upstream", URL: "git@github.com:me/proj.git", Alias: "origin"},
+		{Sym: "other", URL: "https://github.com/them/proj", Alias: "them"},
+	}
+	want := []bool{false, true, false}
+	for i, rem := range remotes {
+		if got := isDuplicateAlias(remotes, rem); got != want[i] {
+			t.Errorf("isDuplicateAlias(%s) = %v, want %v", rem.Sym, got, want[i])
+		}
+	}
+}
+
+func TestMigrateToYolo(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	up := filepath.Join(dir, "up")
+	gitT(t, dir, "init", "-q", "-b", "master", up)
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "old")
+	old := gitT(t, up, "rev-parse", "HEAD")
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "new")
+
+	// the superproject with the submodule checked out at the older commit.
+	super := filepath.Join(dir, "emacs.d")
+	gitT(t, dir, "init", "-q", "-b", "master", super)
+	gitT(t, super, "submodule", "add", "-q", up, "notElpa/foo")
+	sub := filepath.Join(super, "notElpa", "foo")
+	gitT(t, sub, "reset", "-q", "--hard", old)
+	gitT(t, super, "commit", "-q", "-am", "add foo")
+
+	cfgPath := filepath.Join(dir, "repos.jsonc")
+	writeFileT(t, cfgPath, `{
+  "repos": [
+    {"name": "foo", "folder": "~/elsewhere/foo", "branchMain": "master", "branchUse": "master",
+     "remotes": [{"sym": "upstream", "url": "`+up+`", "alias": "origin"}], "remoteDefault": "upstream"}
+  ]
+}`)
+	// the folder on this machine is in the overlay. the migrate must change it there.
+	localPath := filepath.Join(dir, "repos.local.jsonc")
+	writeFileT(t, localPath, `{ "repos": [ {"name": "foo", "folder": "`+sub+`"} ] }`)
+	var out strings.Builder
+	env := &Env{Home: home, ConfigPath: cfgPath, Out: &out}
+	env.Settings.YoloRoot = filepath.Join(super, "notElpaYolo")
+	yolo := filepath.Join(super, "notElpaYolo", "foo")
+
+	// a commit only in the submodule would be lost.
+	gitT(t, sub, "commit", "-q", "--allow-empty", "-m", "local")
+	err := MigrateToYolo(ctx, env, loadRepoT(t, cfgPath, "foo"), "", false, false)
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Fatalf("MigrateToYolo() = %v, want a refusal for the unpushed commit", err)
+	}
+	if ok, _ := exists(yolo); ok {
+		t.Errorf("refused migrate still cloned %s", yolo)
+	}
+	if got := gitT(t, super, "ls-files", "notElpa/foo"); got != "notElpa/foo" {
+		t.Errorf("refused migrate still removed the submodule. ls-files: %q", got)
+	}
+	gitT(t, sub, "reset", "-q", "--hard", old)
+
+	if err = MigrateToYolo(ctx, env, loadRepoT(t, cfgPath, "foo"), "", false, false); err != nil {
+		t.Fatalf("MigrateToYolo() = %v\n%s", err, out.String())
+	}
+
+	// the clone is at the submodule's commit, not the upstream's latest.
+	if got := gitT(t, yolo, "rev-parse", "HEAD"); got != old {
+		t.Errorf("clone HEAD %s, want the submodule's commit %s", got, old)
+	}
+	if got := gitT(t, yolo, "rev-parse", "--abbrev-ref", "HEAD"); got != "master" {
+		t.Errorf("clone branch %s, want master", got)
+	}
+	if got := gitT(t, yolo, "remote", "get-url", "origin"); got != up {
+		t.Errorf("clone origin %s, want %s", got, up)
+	}
+	// the submodule is gone from the superproject, its git dir too.
+	if got := gitT(t, super, "ls-files", "notElpa/foo"); got != "" {
+		t.Errorf("submodule still in the index: %q", got)
+	}
+	if ok, _ := exists(sub); ok {
+		t.Errorf("submodule folder %s still exists", sub)
+	}
+	if ok, _ := exists(filepath.Join(super, ".git", "modules", "notElpa", "foo")); ok {
+		t.Errorf(".git/modules/notElpa/foo still exists")
+	}
+	// the config points at the clone.
+	repo := loadRepoT(t, cfgPath, "foo")
+	if !repo.IsYolo || filepath.Clean(env.expand(repo.Folder)) != yolo {
+		t.Errorf("config has folder %s isYolo %v, want %s true", repo.Folder, repo.IsYolo, yolo)
+	}
+	if src, err := os.ReadFile(cfgPath); err != nil || !strings.Contains(string(src), `"~/elsewhere/foo"`) {
+		t.Errorf("%s changed, want folder only set in the overlay:\n%s", cfgPath, src)
+	}
+	ignore, err := os.ReadFile(filepath.Join(super, ".gitignore"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(ignore), "/notElpaYolo/foo/\n") {
+		t.Errorf(".gitignore is %q, want the clone ignored", ignore)
+	}
+}
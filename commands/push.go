@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// titles of the push report sections.
+const (
+	// only includes repos that had new commits to push.
+	sectionPushed = "Repos pushed"
+	// nothing to push.
+	sectionUpToDate = "Repos already up to date"
+	// usually non-fast-forward, needs a merge/rebase or --force-with-lease
+	sectionRejected = "REJECTED"
+)
+
+// push BranchMain and BranchUse to the "mine" remotes. the "mine" remotes are my forks
+// or personal projects so it's OK to push to them without review.
+// Non-fast-forward pushes are rejected unless forceWithLease is true.
+func PushMine(ctx context.Context, env *Env, repos []config.GitRepo, forceWithLease bool) report.Report {
+	return pushRunner(env, forceWithLease).run(ctx, repos)
+}
+
+// PushMineEach pushes like PushMine but sends the result of each repo as soon as it's done.
+func PushMineEach(ctx context.Context, env *Env, repos []config.GitRepo, forceWithLease bool) <-chan RepoResult {
+	return pushRunner(env, forceWithLease).each(ctx, repos)
+}
+
+func pushRunner(env *Env, forceWithLease bool) runner {
+	return runner{
+		sections: []string{sectionPushed, sectionUpToDate, sectionRejected},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return push(ctx, env, i, repo, forceWithLease)
+		},
+		summary: func(t tally) string {
+			// print # of remotes pushed, duration. rejected pushes are failed.
+			mineCnt := t.repos - t.skipped
+			return fmt.Sprintf("Pushed to %d of %d remotes. time elapsed: %v", mineCnt-t.failed, mineCnt, t.elapsed)
+		},
+	}
+}
+
+// push BranchMain and BranchUse of repo to my remote. i is the position of the repo, shown in the report.
+func push(ctx context.Context, env *Env, i int, repo config.GitRepo, forceWithLease bool) RepoResult {
+	result := newResult(i, repo)
+
+	remoteMine, err := repo.RemoteMine()
+	// this err just means no "mine" remote was configured in the
+	// jsonc. so don't add to the failures, just skip.
+	hasRemoteMine := err == nil
+	if !hasRemoteMine {
+		result.skip()
+		return result
+	}
+
+	// only push branches that exist locally. a missing local branch is not an error,
+	// it just means i never checked it out (see createLocalBranches).
+	branches := make([]string, 0, 2)
+	candidates := []string{repo.BranchMain}
+	if repo.BranchUse != repo.BranchMain {
+		candidates = append(candidates, repo.BranchUse)
+	}
+	for _, br := range candidates {
+		hasBranch, err := gitops.HasLocalBranch(ctx, env.expand(repo.Folder), br)
+		if err != nil {
+			result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, "problem checking for local branch existence: "+err.Error()))
+			return result
+		}
+		if hasBranch {
+			branches = append(branches, br)
+		}
+	}
+	if len(branches) == 0 {
+		return result // nothing to push
+	}
+
+	// git push --porcelain origin master mine
+	args := []string{"push", "--porcelain"}
+	if forceWithLease {
+		args = append(args, "--force-with-lease")
+	}
+	args = append(args, remoteMine.Alias)
+	args = append(args, branches...)
+	cmd := gitops.Command(ctx, env.expand(repo.Folder), args...)
+	// Run git push! git exits with an error when a ref is rejected, so inspect the
+	// porcelain output before deciding it's a failure.
+	stdout, err := gitops.CombinedOutput(cmd)
+	output := string(stdout)
+	statuses := gitops.ParsePushPorcelain(output)
+
+	rejected, pushed := false, false
+	for _, st := range statuses {
+		switch st.Flag {
+		case gitops.PushRejected:
+			rejected = true
+		case gitops.PushUpToDate:
+			// nothing
+		default:
+			pushed = true
+		}
+	}
+	switch {
+	case rejected:
+		result.add(sectionRejected, fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, output))
+		result.Outcome = Failed
+	case err != nil:
+		result.fail(fmt.Sprintf("%d: %s %v %s %s\n", i, repo.Folder, cmd.Args, err.Error(), output))
+	case pushed:
+		result.add(sectionPushed, fmt.Sprintf("%d: %s %v %s\n", i, repo.Folder, cmd.Args, output))
+	default:
+		result.add(sectionUpToDate, fmt.Sprintf("%d: %s\n", i, repo.Folder))
+		result.Outcome = Unchanged
+	}
+	return result
+}
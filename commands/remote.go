@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"fmt"
+
+	"gitFetchHelper/config"
+	"golang.org/x/exp/slices"
+)
+
+// RemoteType selects the remotes of each repo an operation like fetch or diff works on.
+type RemoteType int
+
+const (
+	RemoteUpstream RemoteType = iota + 1
+	RemoteMine
+	RemoteDefault
+	// any remote identified by an arbitrary Sym. Repos without the Sym are skipped.
+	RemoteSym
+	// every remote configured for the repo.
+	RemoteAll
+)
+
+// the name of the remote type as used in command names. ie "Mine" in "diffMine".
+func (rt RemoteType) String() string {
+	switch rt {
+	case RemoteUpstream:
+		return "Upstream"
+	case RemoteMine:
+		return "Mine"
+	case RemoteDefault:
+		return "Default"
+	case RemoteSym:
+		return "Sym"
+	case RemoteAll:
+		return "All"
+	}
+	return fmt.Sprintf("RemoteType(%d)", int(rt))
+}
+
+// get the remotes of repo targeted by remoteType. sym is only used for RemoteSym.
+// An empty slice with a nil error means the repo doesn't have the remote and should be
+// skipped quietly. A missing upstream/mine/default remote is still an error.
+func RemotesFor(repo *config.GitRepo, remoteType RemoteType, sym string) ([]config.Remote, error) {
+	var remote config.Remote
+	var err error
+	switch remoteType {
+	case RemoteUpstream:
+		remote, err = repo.RemoteUpstream()
+	case RemoteDefault:
+		remote, err = repo.RemoteDefault()
+	case RemoteMine:
+		remote, err = repo.RemoteMine()
+	case RemoteSym:
+		remote, err = repo.GetRemoteBySym(sym)
+		if err != nil {
+			return []config.Remote{}, nil // ad-hoc sym not configured for this repo. skip
+		}
+	case RemoteAll:
+		// several syms may share 1 alias (ie my own projects where "mine" and "upstream"
+		// are the same "origin"). only include each alias once.
+		remotes := make([]config.Remote, 0, len(repo.Remotes))
+		for _, rem := range repo.Remotes {
+			dupe := slices.ContainsFunc(remotes, func(r config.Remote) bool { return r.Alias == rem.Alias })
+			if !dupe {
+				remotes = append(remotes, rem)
+			}
+		}
+		return remotes, nil
+	default:
+		return nil, fmt.Errorf("unknown remote type: %v", remoteType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []config.Remote{remote}, nil
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+)
+
+// add a repo to the config file env.ConfigPath. repos is every configured repo, from
+// the included files and the overlay too. The name and folder must not be used by any of
+// them, a later file would silently override the new repo or be overridden by it.
+// If clone is true also clone it and set up its remotes right away instead of waiting
+// for the next cloneYoloRepos/setRemotes. Progress is printed to env.Out.
+func Add(ctx context.Context, env *Env, repos []config.GitRepo, repo *config.GitRepo, clone bool) error {
+	if clone && !repo.IsYolo {
+		return fmt.Errorf("--clone is only for yolo repos. submodules come with the .emacs.d/ repo")
+	}
+	folder := filepath.Clean(env.expand(repo.Folder))
+	for i := range repos {
+		if repos[i].Name == repo.Name {
+			return fmt.Errorf("%s is already defined in %s", repo.Name, repos[i].Source)
+		}
+		if filepath.Clean(env.expand(repos[i].Folder)) == folder {
+			return fmt.Errorf("folder %s is already used by %s, defined in %s", repo.Folder, repos[i].Name, repos[i].Source)
+		}
+	}
+	if err := config.AddRepo(env.ConfigPath, repo); err != nil {
+		return err
+	}
+	repo.Source = env.ConfigPath
+	fmt.Fprintf(env.Out, "Added %s to %s\n", repo.Name, env.ConfigPath)
+	if !clone {
+		return nil
+	}
+
+	if err := os.MkdirAll(parentDir(env.expand(repo.Folder)), os.ModePerm); err != nil {
+		return err
+	}
+	result := cloneYolo(ctx, env, 0, *repo, "")
+	if result.Outcome != Failed {
+		result.merge(setRemotes(ctx, env, 0, *repo, false))
+	}
+
+	fmt.Fprint(env.Out, result.Lines[sectionCloned], result.Lines[sectionRemotesSet])
+	if result.Outcome == Failed {
+		return fmt.Errorf("%s", result.Lines[sectionFailures])
+	}
+	return nil
+}
+
+// remove a repo from its config file. If archive is true the yolo folder is also moved
+// into archiveDir, but only if it has no uncommitted changes or unpushed commits.
+// force skips those checks. Submodules are left on disk, use git rm for those.
+// Progress is printed to env.Out.
+func Remove(ctx context.Context, env *Env, repo config.GitRepo, archive bool, archiveDir string, force bool) error {
+	folder := env.expand(repo.Folder)
+	folderExists, _ := exists(folder)
+	archive = archive && folderExists
+	if archive && !repo.IsYolo {
+		return fmt.Errorf("%s is a git submodule. remove it with git rm instead of --archive", repo.Folder)
+	}
+
+	// check before touching anything so a refusal leaves the config as is.
+	if archive && !force {
+		var problems strings.Builder
+		status, err := gitops.UncommittedChanges(ctx, folder)
+		if err != nil {
+			return err
+		}
+		if status != "" {
+			problems.WriteString("uncommitted changes:\n" + status)
+		}
+		unpushed, err := gitops.UnpushedCommits(ctx, folder)
+		if err != nil {
+			return err
+		}
+		if unpushed != "" {
+			problems.WriteString("commits not on any remote:\n" + unpushed)
+		}
+		if problems.Len() > 0 {
+			return fmt.Errorf("not archiving %s. use --force to archive anyway.\n%s", repo.Folder, problems.String())
+		}
+	}
+
+	// an override left in an overlay would add the repo back with just the overridden fields.
+	for _, path := range append([]string{repo.Source}, repo.OverriddenIn...) {
+		if err := config.RemoveRepo(path, repo.Name); err != nil {
+			return err
+		}
+		fmt.Fprintf(env.Out, "Removed %s from %s\n", repo.Name, path)
+	}
+	if !archive {
+		return nil
+	}
+
+	target, err := moveToArchive(env, folder, archiveDir, filepath.Base(folder))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Out, "Moved %s to %s\n", folder, target)
+	return nil
+}
+
+// move folder into archiveDir as name. Returns where it went. If an earlier move took
+// name a time suffix is added, the older copy is never clobbered.
+func moveToArchive(env *Env, folder, archiveDir, name string) (string, error) {
+	archiveDir = env.expand(archiveDir)
+	if err := os.MkdirAll(archiveDir, os.ModePerm); err != nil {
+		return "", err
+	}
+	target := filepath.Join(archiveDir, name)
+	if targetExists, _ := exists(target); targetExists {
+		target += "-" + time.Now().Format("20060102-150405")
+	}
+	if err := os.Rename(folder, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func TestRemoveArchive(t *testing.T) {
+	// a yolo clone of up in dir/yolo/foo, configured in repos.jsonc with an override
+	// in repos.local.jsonc.
+	setup := func(t *testing.T) (dir string, env *Env, repo config.GitRepo) {
+		dir = t.TempDir()
+		up := filepath.Join(dir, "up")
+		gitT(t, dir, "init", "-q", "-b", "master", up)
+		gitT(t, up, "commit", "-q", "--allow-empty", "-m", "init")
+		folder := filepath.Join(dir, "yolo", "foo")
+		gitT(t, dir, "clone", "-q", up, folder)
+
+		cfgPath := filepath.Join(dir, "repos.jsonc")
+		writeFileT(t, cfgPath, `{
+  "repos": [
+    {"name": "keep", "folder": "~/keep"},
+    {"name": "foo", "folder": "`+folder+`", "isYolo": true, "branchMain": "master", "branchUse": "master",
+     "remotes": [{"sym": "upstream", "url": "`+up+`", "alias": "origin"}], "remoteDefault": "upstream"}
+  ]
+}`)
+		writeFileT(t, filepath.Join(dir, "repos.local.jsonc"), `{
+  "repos": [
+    {"name": "foo", "branchUse": "master"}
+  ]
+}`)
+		env = &Env{Home: home, ConfigPath: cfgPath, Out: io.Discard}
+		return dir, env, loadRepoT(t, cfgPath, "foo")
+	}
+	// true if the repo is in none of the config files.
+	removedFromConfig := func(t *testing.T, dir string) bool {
+		t.Helper()
+		cfg, err := config.Load(filepath.Join(dir, "repos.jsonc"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, found := config.FindRepo(cfg.Repos, "foo")
+		_, kept := config.FindRepo(cfg.Repos, "keep")
+		if !kept {
+			t.Errorf("other repo removed too")
+		}
+		return !found
+	}
+
+	refusals := []struct {
+		name string
+		// make the clone unsafe to archive.
+		dirty func(t *testing.T, folder string)
+		want  string
+	}{
+		{"uncommitted", func(t *testing.T, folder string) {
+			writeFileT(t, filepath.Join(folder, "new.txt"), "new")
+		}, "uncommitted changes"},
+		{"unpushed", func(t *testing.T, folder string) {
+			gitT(t, folder, "commit", "-q", "--allow-empty", "-m", "local")
+		}, "commits not on any remote"},
+	}
+	for _, tt := range refusals {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, env, repo := setup(t)
+			folder := repo.Folder
+			archiveDir := filepath.Join(dir, "archive")
+			tt.dirty(t, folder)
+
+			err := Remove(context.Background(), env, repo, true, archiveDir, false)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Remove() = %v, want a refusal with %q", err, tt.want)
+			}
+			if removedFromConfig(t, dir) {
+				t.Errorf("refused remove still edited the config")
+			}
+			if ok, _ := exists(folder); !ok {
+				t.Errorf("refused remove still moved %s", folder)
+			}
+
+			// --force archives anyway.
+			if err = Remove(context.Background(), env, repo, true, archiveDir, true); err != nil {
+				t.Fatal(err)
+			}
+			if !removedFromConfig(t, dir) {
+				t.Errorf("foo still in the config files")
+			}
+			if ok, _ := exists(folder); ok {
+				t.Errorf("%s not moved", folder)
+			}
+			if ok, _ := exists(filepath.Join(archiveDir, "foo", ".git")); !ok {
+				t.Errorf("%s not archived", folder)
+			}
+		})
+	}
+
+	t.Run("target exists", func(t *testing.T) {
+		dir, env, repo := setup(t)
+		archiveDir := filepath.Join(dir, "archive")
+		older := filepath.Join(archiveDir, "foo")
+		writeFileT(t, filepath.Join(older, "old.txt"), "old")
+
+		if err := Remove(context.Background(), env, repo, true, archiveDir, false); err != nil {
+			t.Fatal(err)
+		}
+		if ok, _ := exists(filepath.Join(older, "old.txt")); !ok {
+			t.Errorf("older archive clobbered")
+		}
+		entries, err := os.ReadDir(archiveDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 || !strings.HasPrefix(entries[1].Name(), "foo-") {
+			t.Errorf("archive has %v, want foo and foo-<time>", entries)
+		}
+		if !removedFromConfig(t, dir) {
+			t.Errorf("foo still in the config files")
+		}
+	})
+
+	t.Run("overlay", func(t *testing.T) {
+		dir, env, repo := setup(t)
+		if len(repo.OverriddenIn) != 1 {
+			t.Fatalf("OverriddenIn = %v, want repos.local.jsonc", repo.OverriddenIn)
+		}
+		// without --archive the folder stays.
+		if err := Remove(context.Background(), env, repo, false, filepath.Join(dir, "archive"), false); err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range []string{repo.Source, repo.OverriddenIn[0]} {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(src), `"foo"`) {
+				t.Errorf("foo still in %s:\n%s", path, src)
+			}
+		}
+		if ok, _ := exists(repo.Folder); !ok {
+			t.Errorf("folder moved without --archive")
+		}
+	})
+}
+
+func TestAddDefinedElsewhere(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "repos.jsonc")
+	writeFileT(t, cfgPath, `{
+  "include": ["work.jsonc"],
+  "repos": [
+    {"name": "a", "folder": "~/a"}
+  ]
+}`)
+	workPath := filepath.Join(dir, "work.jsonc")
+	writeFileT(t, workPath, `{ "repos": [ {"name": "w", "folder": "~/w"} ] }`)
+	localPath := filepath.Join(dir, "repos.local.jsonc")
+	writeFileT(t, localPath, `{ "repos": [ {"name": "l", "folder": "~/l"} ] }`)
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &Env{Home: home, ConfigPath: cfgPath, Out: io.Discard}
+	before, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name, folder string
+		want         string
+	}{
+		{"w", "~/new", "w is already defined in " + workPath},
+		{"l", "~/new", "l is already defined in " + localPath},
+		{"a", "~/new", "a is already defined in " + cfgPath},
+		{"new", "~/w", "folder ~/w is already used by w, defined in " + workPath},
+	}
+	for _, tt := range tests {
+		repo := config.NewGitRepo(config.Settings{}, tt.name, "https://example.com/"+tt.name, "", "master", tt.folder, true)
+		err := Add(context.Background(), env, cfg.Repos, &repo, false)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Add(%s %s) = %v, want %q", tt.name, tt.folder, err, tt.want)
+		}
+	}
+	if after, err := os.ReadFile(cfgPath); err != nil || string(after) != string(before) {
+		t.Errorf("refused adds changed %s:\n%s", cfgPath, after)
+	}
+
+	repo := config.NewGitRepo(config.Settings{}, "new", "https://example.com/new", "", "master", "~/new", true)
+	if err := Add(context.Background(), env, cfg.Repos, &repo, false); err != nil {
+		t.Fatalf("Add(new) = %v", err)
+	}
+	if got := loadRepoT(t, cfgPath, "new"); got.Source != cfgPath || got.Folder != "~/new" {
+		t.Errorf("added repo from %s folder %s, want %s ~/new", got.Source, got.Folder, cfgPath)
+	}
+}
@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+
+	"gitFetchHelper/config"
+)
+
+// title of the report section every operation has.
+const sectionFailures = "FAILURES"
+
+// Outcome is what an operation did to 1 repo.
+type Outcome int
+
+const (
+	// nothing to do. ie no new data to fetch.
+	Unchanged Outcome = iota
+	// the repo was changed, or has something to report. ie new data fetched, drift found.
+	Changed
+	// at least 1 step failed. see the FAILURES lines.
+	Failed
+	// the repo doesn't have what the operation works on. ie no "mine" remote. only counted.
+	Skipped
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Unchanged:
+		return "unchanged"
+	case Changed:
+		return "changed"
+	case Failed:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// RepoResult is what an operation did to 1 repo. The *Each functions send 1 per repo as
+// soon as the repo is done, so a caller can show progress.
+type RepoResult struct {
+	// position of the repo in the repos given to the operation.
+	Index   int
+	Repo    config.GitRepo
+	Outcome Outcome
+	// report lines of the repo by section title. ie "FAILURES". A repo may add to several
+	// sections, ie a remote created then a failed fetch of it. None if there was nothing to do.
+	Lines map[string]string
+}
+
+// start the result of the repo at position i.
+func newResult(i int, repo config.GitRepo) RepoResult {
+	return RepoResult{Index: i, Repo: repo}
+}
+
+// add a line to the section titled title. The repo is Changed unless it already failed.
+func (r *RepoResult) add(title, line string) {
+	if r.Lines == nil {
+		r.Lines = make(map[string]string, 1)
+	}
+	r.Lines[title] += line
+	if r.Outcome == Unchanged {
+		r.Outcome = Changed
+	}
+}
+
+// add a line to the failures. The repo is Failed even if other steps changed it.
+func (r *RepoResult) fail(line string) {
+	r.add(sectionFailures, line)
+	r.Outcome = Failed
+}
+
+// the repo is not one the operation works on.
+func (r *RepoResult) skip() {
+	r.Outcome = Skipped
+}
+
+// add the lines of other, ie a later step on the same repo.
+func (r *RepoResult) merge(other RepoResult) {
+	for title, line := range other.Lines {
+		r.add(title, line)
+	}
+	if other.Outcome == Failed {
+		r.Outcome = Failed
+	}
+}
+
+// true if the repo added a line to the section titled title.
+func (r *RepoResult) has(title string) bool {
+	_, ok := r.Lines[title]
+	return ok
+}
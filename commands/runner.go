@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/report"
+)
+
+// an operation that runs on each repo concurrently, then reports all the repos at once.
+// The operations only say what they do to 1 repo, the runner does the rest.
+type runner struct {
+	// titles of the report sections, in order. the failures section is always added last.
+	sections []string
+	// the operation on 1 repo. called concurrently, 1 goroutine per repo.
+	// i is the position of the repo, shown in the report.
+	repo func(ctx context.Context, i int, repo config.GitRepo) RepoResult
+	// the summary of the report. ie "Fetched 3 of 4 remotes".
+	summary func(t tally) string
+}
+
+// the # of repos of a run by outcome, and how long the run took.
+type tally struct {
+	repos     int
+	changed   int
+	unchanged int
+	failed    int
+	skipped   int
+	elapsed   time.Duration
+}
+
+// summary of an operation on 1 kind of remote. ie "Fetched 3 of 4 remotes".
+// Failed repos are not counted as done, skipped repos don't have the remote.
+func (t tally) remotes(verb string) string {
+	summary := fmt.Sprintf("%s %d of %d remotes. time elapsed: %v",
+		verb, t.repos-t.skipped-t.failed, t.repos-t.skipped, t.elapsed)
+	if t.skipped > 0 {
+		summary += fmt.Sprintf("\nSkipped %d repos without a matching remote.", t.skipped)
+	}
+	return summary
+}
+
+// run op on each repo. The results are sent in the order they finish and the channel is
+// closed after the last one. The channel has room for every result so a caller may stop
+// reading early without leaking goroutines.
+func (op runner) each(ctx context.Context, repos []config.GitRepo) <-chan RepoResult {
+	results := make(chan RepoResult, len(repos))
+	wg := sync.WaitGroup{}
+	for i := range repos {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- op.repo(ctx, i, repos[i])
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// run op on each repo, measure time, report.
+func (op runner) run(ctx context.Context, repos []config.GitRepo) report.Report {
+	start := time.Now() // stop watch start
+
+	results := make([]RepoResult, 0, len(repos))
+	for r := range op.each(ctx, repos) {
+		results = append(results, r)
+	}
+	// repos finish in any order. report them in config order so 2 runs are easy to compare.
+	sort.Slice(results, func(a, b int) bool { return results[a].Index < results[b].Index })
+
+	t := tally{repos: len(repos)}
+	for i := range results {
+		switch results[i].Outcome {
+		case Changed:
+			t.changed++
+		case Unchanged:
+			t.unchanged++
+		case Failed:
+			t.failed++
+		case Skipped:
+			t.skipped++
+		}
+	}
+	t.elapsed = time.Since(start) // stop watch end
+	return report.Report{Summary: op.summary(t), Sections: op.render(results)}
+}
+
+// the report sections with the lines of results. a section lists every repo with a line
+// in it, repos with nothing to say are only counted in the summary.
+func (op runner) render(results []RepoResult) []report.Section {
+	titles := append(append(make([]string, 0, len(op.sections)+1), op.sections...), sectionFailures)
+	secs := make([]report.Section, 0, len(titles))
+	for _, title := range titles {
+		lines := make([]string, 0, 4)
+		for i := range results {
+			if line, ok := results[i].Lines[title]; ok {
+				lines = append(lines, line)
+			}
+		}
+		secs = append(secs, report.Section{Title: title, Lines: lines})
+	}
+	return secs
+}
@@ -0,0 +1,119 @@
+package commands
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gitFetchHelper/config"
+)
+
+func TestRunner(t *testing.T) {
+	repos := []config.GitRepo{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+	var got tally
+	r := runner{
+		sections: []string{"DONE"},
+		repo: func(_ context.Context, i int, repo config.GitRepo) RepoResult {
+			result := newResult(i, repo)
+			switch repo.Name {
+			case "a":
+				// finish last so results come in out of order.
+				time.Sleep(20 * time.Millisecond)
+				result.add("DONE", "a\n")
+			case "b":
+				result.add("DONE", "b\n")
+				result.fail("b failed\n")
+			case "c":
+				result.skip()
+			}
+			return result
+		},
+		summary: func(t tally) string {
+			got = t
+			return "summary"
+		},
+	}.run(context.Background(), repos)
+
+	want := tally{repos: 4, changed: 1, unchanged: 1, failed: 1, skipped: 1, elapsed: got.elapsed}
+	if got != want {
+		t.Errorf("tally %+v, want %+v", got, want)
+	}
+	if r.Summary != "summary" || len(r.Sections) != 2 {
+		t.Fatalf("report %+v, want the summary, DONE and FAILURES", r)
+	}
+	if done := r.Sections[0]; done.Title != "DONE" || !slices.Equal(done.Lines, []string{"a\n", "b\n"}) {
+		t.Errorf("section %+v, want a and b in repo order", done)
+	}
+	if fails := r.Sections[1]; fails.Title != sectionFailures || !slices.Equal(fails.Lines, []string{"b failed\n"}) {
+		t.Errorf("section %+v, want the failure of b", fails)
+	}
+}
+
+// 2 operations on different repo sets of 1 process don't share state.
+func TestEachSubsetsConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	up := filepath.Join(dir, "up")
+	gitT(t, dir, "init", "-q", "-b", "master", up)
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "init")
+
+	repos := make([]config.GitRepo, 0, 4)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		folder := filepath.Join(dir, name)
+		gitT(t, dir, "clone", "-q", up, folder)
+		repos = append(repos, config.GitRepo{
+			Name:       name,
+			Folder:     folder,
+			Remotes:    []config.Remote{{Sym: "upstream", URL: up, Alias: "origin"}},
+			BranchMain: "master",
+			BranchUse:  "master",
+		})
+	}
+	repos[3].Remotes[0].Sym = "mine" // no remote with the "upstream" Sym to fetch
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "new")
+
+	env := &Env{Home: home}
+	collect := func(ch <-chan RepoResult) []RepoResult {
+		results := make([]RepoResult, 0, 2)
+		for r := range ch {
+			results = append(results, r)
+		}
+		return results
+	}
+	var fetched, diffed []RepoResult
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		fetched = collect(FetchEach(context.Background(), env, repos[2:], FetchOptions{Remote: RemoteSym, Sym: "upstream"}))
+	}()
+	go func() {
+		defer wg.Done()
+		diffed = collect(DiffEach(context.Background(), env, repos[:2], RemoteUpstream, ""))
+	}()
+	wg.Wait()
+
+	sort.Slice(fetched, func(i, j int) bool { return fetched[i].Index < fetched[j].Index })
+	if len(fetched) != 2 || fetched[0].Repo.Name != "c" || fetched[1].Repo.Name != "d" {
+		t.Fatalf("fetch results %+v, want repos c and d", fetched)
+	}
+	if fetched[0].Outcome != Changed || !fetched[0].has(sectionFetched) {
+		t.Errorf("fetch c: got %q, want new data fetched", fetched[0].Lines)
+	}
+	if fetched[1].Outcome != Skipped || len(fetched[1].Lines) != 0 {
+		t.Errorf("fetch d: got %+v, want skipped without lines", fetched[1])
+	}
+
+	if len(diffed) != 2 {
+		t.Fatalf("diff results %+v, want 2", diffed)
+	}
+	for _, r := range diffed {
+		// the new commit was never fetched so there's nothing to diff against.
+		if r.Outcome != Unchanged || len(r.Lines) != 0 {
+			t.Errorf("diff %s: got %v %q, want unchanged", r.Repo.Name, r.Outcome, r.Lines)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// title of the set remotes report section. 1 line per remote created.
+const sectionRemotesSet = "NEW remotes set"
+
+// Set up every configured remote that is missing.
+// Useful after a fresh emacs config clone to a new computer. Or after getting latest
+// when a new package has been added. A fresh clone of my fork is missing the upstream
+// and any other remotes. If fetch is true newly added remotes are fetched too.
+func SetRemotes(ctx context.Context, env *Env, repos []config.GitRepo, fetch bool) report.Report {
+	return setRemotesRunner(env, fetch).run(ctx, repos)
+}
+
+// SetRemotesEach sets remotes like SetRemotes but sends the result of each repo as soon as it's done.
+func SetRemotesEach(ctx context.Context, env *Env, repos []config.GitRepo, fetch bool) <-chan RepoResult {
+	return setRemotesRunner(env, fetch).each(ctx, repos)
+}
+
+func setRemotesRunner(env *Env, fetch bool) runner {
+	return runner{
+		sections: []string{sectionRemotesSet},
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return setRemotes(ctx, env, i, repo, fetch)
+		},
+		summary: func(t tally) string {
+			// print # of repos checked, duration
+			return fmt.Sprintf("Checked for configured remotes on %d repos. time elapsed: %v", t.repos, t.elapsed)
+		},
+	}
+}
+
+func setRemotes(ctx context.Context, env *Env, i int, repo config.GitRepo, fetch bool) RepoResult {
+	result := newResult(i, repo)
+
+	actual, err := gitops.Remotes(ctx, env.expand(repo.Folder))
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+
+	for _, d := range compareRemotes(repo.Remotes, actual) {
+		switch d.kind {
+		case driftMismatch:
+			// don't clobber a URL that may have been changed on purpose. audit --fix does that.
+			// note: in msg below config: and actual: are same len for visual alignment of url strings.
+			result.fail(fmt.Sprintf("%d: %s mismatched %s URL.\nconfig: %s\nactual: %s\n\n",
+				i, repo.Folder, d.alias, d.configURL, d.actualURL))
+			continue
+		case driftExtra:
+			continue
+		}
+
+		// run git command: git remote add {alias} {url}
+		cmd := gitops.Command(ctx, env.expand(repo.Folder), "remote", "add", d.alias, d.configURL)
+		createOutput, err := gitops.CombinedOutput(cmd)
+		if err != nil {
+			result.fail(fmt.Sprintf("%d: %s %v %s %s\n", i, repo.Folder, cmd.Args, err.Error(), createOutput))
+			continue
+		}
+		created := fmt.Sprintf("%d: %s %v\n", i, repo.Folder, cmd.Args)
+
+		if fetch {
+			// run git command: git fetch {alias}
+			cmd = gitops.Command(ctx, env.expand(repo.Folder), "fetch", d.alias)
+			fetchOutput, err := gitops.CombinedOutput(cmd)
+			if err != nil {
+				// the remote is still created. report both.
+				result.fail(fmt.Sprintf("%d: %s %v %s %s\n", i, repo.Folder, cmd.Args, err.Error(), fetchOutput))
+			} else {
+				created += fmt.Sprintf("%d: %s %v\n", i, repo.Folder, cmd.Args)
+			}
+		}
+		// SUCCESS, remote created
+		result.add(sectionRemotesSet, created)
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func TestSetRemotes(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	up := filepath.Join(dir, "up")
+	gitT(t, dir, "init", "-q", "-b", "master", up)
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "first")
+	fork := filepath.Join(dir, "fork")
+	gitT(t, dir, "clone", "-q", up, fork)
+
+	foo := filepath.Join(dir, "foo")
+	gitT(t, dir, "clone", "-q", up, foo)
+	gitT(t, foo, "remote", "add", "old", "https://example.com/old")
+	bar := filepath.Join(dir, "bar")
+	gitT(t, dir, "clone", "-q", up, bar)
+	// only the remotes set up now are fetched, origin stays behind.
+	originBefore := gitT(t, foo, "rev-parse", "origin/master")
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "second")
+
+	repos := []config.GitRepo{
+		{Name: "foo", Folder: foo, Remotes: []config.Remote{
+			{Sym: "upstream", URL: up, Alias: "origin"},
+			// mine and backup share the fork alias. it's only added once.
+			{Sym: "mine", URL: fork, Alias: "fork"},
+			{Sym: "backup", URL: fork, Alias: "fork"},
+		}},
+		{Name: "bar", Folder: bar, Remotes: []config.Remote{
+			{Sym: "upstream", URL: "https://example.com/moved", Alias: "origin"},
+		}},
+	}
+	env := &Env{Home: home, Out: io.Discard}
+	rep := SetRemotes(ctx, env, repos, true)
+
+	sections := make(map[string]string, len(rep.Sections))
+	for _, sec := range rep.Sections {
+		sections[sec.Title] = strings.Join(sec.Lines, "")
+	}
+	if got := sections[sectionRemotesSet]; strings.Count(got, "remote add") != 1 ||
+		!strings.Contains(got, "remote add fork "+fork) || !strings.Contains(got, "fetch fork") {
+		t.Errorf("%s section is %q, want fork added and fetched once", sectionRemotesSet, got)
+	}
+	if got := gitT(t, foo, "remote", "get-url", "fork"); got != fork {
+		t.Errorf("fork remote URL %s, want %s", got, fork)
+	}
+	if got := gitT(t, foo, "rev-parse", "fork/master"); got != gitT(t, fork, "rev-parse", "master") {
+		t.Errorf("fork/master is %s, want the fetched fork master", got)
+	}
+	if got := gitT(t, foo, "rev-parse", "origin/master"); got != originBefore {
+		t.Errorf("origin/master moved to %s, want the existing origin left unfetched", got)
+	}
+	// remotes not in the config are left alone.
+	if got := gitT(t, foo, "remote", "get-url", "old"); got != "https://example.com/old" {
+		t.Errorf("old remote URL %s, want it untouched", got)
+	}
+
+	// a mismatched URL is reported, not rewritten.
+	if got := sections[sectionFailures]; !strings.Contains(got, bar+" mismatched origin URL") ||
+		strings.Contains(got, foo) {
+		t.Errorf("%s section is %q, want only the bar origin mismatch", sectionFailures, got)
+	}
+	if got := gitT(t, bar, "remote", "get-url", "origin"); got != up {
+		t.Errorf("bar origin URL %s, want it left at %s", got, up)
+	}
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"gitFetchHelper/config"
+	"gitFetchHelper/gitops"
+	"gitFetchHelper/report"
+)
+
+// Check the existing yolo folders are healthy clones. cloneYolo assumes an existing
+// folder is the cloned repo, this is where that assumption gets checked.
+
+// check the folder of a yolo repo is a healthy clone. Returns the problems found, none
+// for a healthy clone. A missing folder is not checked, cloneYoloRepos handles that.
+func verifyYoloFolder(ctx context.Context, env *Env, repo *config.GitRepo) []string {
+	folder := filepath.Clean(env.expand(repo.Folder))
+	problems := make([]string, 0, 2)
+
+	if !gitops.IsInRepo(ctx, folder) {
+		return append(problems, "not a git work tree")
+	}
+	// a folder without .git inside the .emacs.d/ repo is still "inside a work tree".
+	// make sure the work tree is the folder itself.
+	toplevel, err := gitops.Output(ctx, folder, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	if !sameFolder(toplevel, folder) {
+		return append(problems, fmt.Sprintf("not a clone. nested inside the git repo %s", toplevel))
+	}
+	if gitops.IsInSubmodule(ctx, folder) {
+		problems = append(problems, "is a git submodule, expected a yolo clone")
+	}
+
+	remote, err := repo.RemoteDefault()
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	url, err := gitops.Output(ctx, folder, "remote", "get-url", remote.Alias)
+	switch {
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("default remote %s is missing", remote.Alias))
+	case url != remote.URL:
+		// note: config: and actual: are same len for visual alignment of url strings.
+		problems = append(problems, fmt.Sprintf("mismatched %s URL.\n    config: %s\n    actual: %s",
+			remote.Alias, remote.URL, url))
+	}
+
+	// BranchUse may only be a remote tracking branch until it's checked out.
+	hasLocal, err := gitops.HasLocalBranch(ctx, folder, repo.BranchUse)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	if !hasLocal {
+		if _, err = gitops.Hash(ctx, folder, "refs/remotes/"+remote.Alias+"/"+repo.BranchUse); err != nil {
+			problems = append(problems, fmt.Sprintf("branchUse %s does not exist", repo.BranchUse))
+		}
+	}
+	return problems
+}
+
+// true if a and b are the same folder. git prints paths with symlinks resolved.
+func sameFolder(a, b string) bool {
+	if evalA, err := filepath.EvalSymlinks(a); err == nil {
+		a = evalA
+	}
+	if evalB, err := filepath.EvalSymlinks(b); err == nil {
+		b = evalB
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// titles of the verify report sections.
+const (
+	sectionBroken = "BROKEN"
+	// only with reclone.
+	sectionRecloned = "Re-cloned"
+)
+
+// verify every yolo repo that exists on disk. With reclone each broken folder is moved
+// to the archive dir (as <name>.broken-<time>, never deleted) and cloned again. Out of
+// the yoloRoot so discover doesn't offer it as a new repo.
+func Verify(ctx context.Context, env *Env, repos []config.GitRepo, reclone bool) report.Report {
+	return verifyRunner(env, reclone).run(ctx, repos)
+}
+
+// VerifyEach verifies like Verify but sends the result of each repo as soon as it's done.
+func VerifyEach(ctx context.Context, env *Env, repos []config.GitRepo, reclone bool) <-chan RepoResult {
+	return verifyRunner(env, reclone).each(ctx, repos)
+}
+
+func verifyRunner(env *Env, reclone bool) runner {
+	sections := []string{sectionBroken}
+	// same suffix for every folder moved aside by this run.
+	suffix := ""
+	if reclone {
+		sections = append(sections, sectionRecloned)
+		suffix = ".broken-" + time.Now().Format("20060102-150405")
+	}
+	return runner{
+		sections: sections,
+		repo: func(ctx context.Context, i int, repo config.GitRepo) RepoResult {
+			return verify(ctx, env, i, repo, suffix)
+		},
+		summary: func(t tally) string {
+			// print # of yolo repos checked, duration
+			return fmt.Sprintf("Verified %d yolo repos. time elapsed: %v", t.repos-t.skipped, t.elapsed)
+		},
+	}
+}
+
+// verify the clone of repo. If recloneSuffix is not "" a broken folder is moved to the
+// archive dir with the suffix and cloned again.
+func verify(ctx context.Context, env *Env, i int, repo config.GitRepo, recloneSuffix string) RepoResult {
+	result := newResult(i, repo)
+
+	if !repo.IsYolo {
+		result.skip()
+		return result
+	}
+	if folderExists, _ := exists(env.expand(repo.Folder)); !folderExists {
+		return result // not cloned yet. cloneYoloRepos will clone it
+	}
+	problems := verifyYoloFolder(ctx, env, &repo)
+	if len(problems) == 0 {
+		return result // no reporting needed for "normal" case of a healthy clone.
+	}
+	for _, p := range problems {
+		result.add(sectionBroken, fmt.Sprintf("%d: %s %s\n", i, repo.Folder, p))
+	}
+	if recloneSuffix == "" {
+		return result
+	}
+
+	folder := filepath.Clean(env.expand(repo.Folder))
+	movedTo, err := moveToArchive(env, folder, env.DefaultArchiveDir(), filepath.Base(folder)+recloneSuffix)
+	if err != nil {
+		result.fail(fmt.Sprintf("%d: %s %s\n", i, repo.Folder, err.Error()))
+		return result
+	}
+	cloned := cloneYolo(ctx, env, i, repo, "")
+	if line, ok := cloned.Lines[sectionCloned]; ok {
+		result.add(sectionRecloned, fmt.Sprintf("%d: %s broken clone moved to %s\n", i, repo.Folder, movedTo)+line)
+	}
+	if line, ok := cloned.Lines[sectionFailures]; ok {
+		result.fail(line)
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func TestVerifyYoloFolder(t *testing.T) {
+	dir := t.TempDir()
+	clone := filepath.Join(dir, "clone")
+	gitT(t, dir, "init", "-q", "-b", "master", clone)
+	gitT(t, clone, "commit", "-q", "--allow-empty", "-m", "init")
+	gitT(t, clone, "remote", "add", "origin", "https://example.com/clone")
+	nested := filepath.Join(clone, "nested")
+	if err := os.Mkdir(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	newRepo := func(folder, url, branch string) *config.GitRepo {
+		return &config.GitRepo{
+			Folder:           folder,
+			Remotes:          []config.Remote{{Sym: "upstream", URL: url, Alias: "origin"}},
+			RemoteDefaultSym: "upstream",
+			BranchMain:       "master",
+			BranchUse:        branch,
+			IsYolo:           true,
+		}
+	}
+	tests := []struct {
+		name string
+		repo *config.GitRepo
+		want []string // substrings of the problems, in order
+	}{
+		{"healthy", newRepo(clone, "https://example.com/clone", "master"), nil},
+		{"not a work tree", newRepo(dir, "https://example.com/clone", "master"), []string{"not a git work tree"}},
+		{"nested", newRepo(nested, "https://example.com/clone", "master"), []string{"nested inside"}},
+		{"url and branch", newRepo(clone, "https://example.com/other", "mine"),
+			[]string{"mismatched origin URL", "branchUse mine does not exist"}},
+	}
+	for _, tt := range tests {
+		got := verifyYoloFolder(context.Background(), &Env{Home: home}, tt.repo)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !strings.Contains(got[i], tt.want[i]) {
+				t.Errorf("%s: got %q, want %q", tt.name, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestVerifyReclone(t *testing.T) {
+	dir := t.TempDir()
+	up := filepath.Join(dir, "up")
+	gitT(t, dir, "init", "-q", "-b", "master", up)
+	gitT(t, up, "commit", "-q", "--allow-empty", "-m", "init")
+	// a clone pointing at the wrong URL.
+	yoloRoot := filepath.Join(dir, "yolo")
+	foo := filepath.Join(yoloRoot, "foo")
+	gitT(t, dir, "clone", "-q", up, foo)
+	gitT(t, foo, "remote", "set-url", "origin", "https://example.com/other")
+
+	repo := config.GitRepo{
+		Name:             "foo",
+		Folder:           foo,
+		Remotes:          []config.Remote{{Sym: "upstream", URL: up, Alias: "origin"}},
+		RemoteDefaultSym: "upstream",
+		BranchMain:       "master",
+		BranchUse:        "master",
+		IsYolo:           true,
+	}
+	env := &Env{Home: home, Out: io.Discard}
+	env.Settings.YoloRoot = yoloRoot
+	rep := Verify(context.Background(), env, []config.GitRepo{repo}, true)
+	for _, sec := range rep.Sections {
+		if sec.Title == sectionFailures && len(sec.Lines) > 0 {
+			t.Fatalf("Verify() failed: %q", sec.Lines)
+		}
+	}
+
+	if got := gitT(t, foo, "remote", "get-url", "origin"); got != up {
+		t.Errorf("recloned origin %s, want %s", got, up)
+	}
+	// the broken clone is kept, but not in the yoloRoot where discover would find it.
+	entries, err := os.ReadDir(yoloRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "foo" {
+		t.Errorf("yoloRoot has %v, want only foo", entries)
+	}
+	moved, err := filepath.Glob(filepath.Join(dir, "yoloArchive", "foo.broken-*"))
+	if err != nil || len(moved) != 1 {
+		t.Fatalf("archive has %v %v, want the broken clone", moved, err)
+	}
+	if got := gitT(t, moved[0], "remote", "get-url", "origin"); got != "https://example.com/other" {
+		t.Errorf("moved clone origin %s, want the broken one", got)
+	}
+}
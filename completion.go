@@ -0,0 +1,450 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gitFetchHelper/config"
+)
+
+// everything the shell completion scripts need to know. The repo names and Syms are
+// baked into the script when it's generated. The scripts don't call back into this
+// program because it reads ./repos.jsonc relative to the current directory.
+type completionSpec struct {
+	// flags given before the command name. ie --config, -v
+	globalFlags []completionFlag
+	commands    []completionCommand
+	// repo names from repos.jsonc
+	repos []string
+	// distinct remote Syms from repos.jsonc. ie "mine", "upstream"
+	syms []string
+}
+
+// a command as seen by shell completion.
+type completionCommand struct {
+	// name followed by aliases
+	names   []string
+	summary string
+	flags   []completionFlag
+	args    argKind
+	choices []string
+}
+
+// a flag as seen by shell completion.
+type completionFlag struct {
+	name  string
+	usage string
+	// false for bool flags
+	takesValue bool
+	value      argKind
+	// the values to complete when value is argChoice.
+	choices []string
+}
+
+// build the completion spec from the CLI commands and the repos.
+func newCompletionSpec(repos []config.GitRepo) completionSpec {
+	spec := completionSpec{
+		repos: make([]string, 0, len(repos)),
+		syms:  make([]string, 0, 8),
+	}
+	seenSym := make(map[string]bool)
+	for _, repo := range repos {
+		spec.repos = append(spec.repos, repo.Name)
+		for _, rem := range repo.Remotes {
+			if !seenSym[rem.Sym] {
+				seenSym[rem.Sym] = true
+				spec.syms = append(spec.syms, rem.Sym)
+			}
+		}
+	}
+	sort.Strings(spec.repos)
+	sort.Strings(spec.syms)
+
+	for _, cmd := range cliCommands() {
+		cc := completionCommand{
+			names:   []string{cmd.name},
+			summary: cmd.summary,
+			args:    cmd.args,
+			choices: cmd.choices,
+		}
+		for _, a := range cmd.aliases {
+			cc.names = append(cc.names, a.name)
+		}
+		// a throw away flag set just to list the flags.
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		cmd.setup(fs)
+		cc.flags = completionFlags(fs, cmd.flagArgs, cmd.flagChoices)
+		spec.commands = append(spec.commands, cc)
+	}
+	spec.globalFlags = completionFlags(globalFlags(), globalFlagArgs, globalFlagChoices)
+	return spec
+}
+
+// the flags of fs as seen by shell completion. flagArgs is what the flag values are,
+// flagChoices the values of the argChoice ones.
+func completionFlags(fs *flag.FlagSet, flagArgs map[string]argKind, flagChoices map[string][]string) []completionFlag {
+	flags := make([]completionFlag, 0, 8)
+	fs.VisitAll(func(f *flag.Flag) {
+		bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+		isBool := ok && bf.IsBoolFlag()
+		flags = append(flags, completionFlag{
+			name:       f.Name,
+			usage:      f.Usage,
+			takesValue: !isBool,
+			value:      flagArgs[f.Name],
+			choices:    flagChoices[f.Name],
+		})
+	})
+	return flags
+}
+
+// the flag as typed on the command line. "-v" for 1 letter flags, "--config" otherwise.
+func flagWord(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// the words to complete for flags. ie "--sym --all-remotes"
+func flagWords(flags []completionFlag) []string {
+	words := make([]string, 0, len(flags))
+	for _, f := range flags {
+		words = append(words, flagWord(f.name))
+	}
+	return words
+}
+
+// "--config|-config|--jobs|-jobs" for the global flags that take a value. The word after
+// them is their value, not the command name.
+func (spec *completionSpec) globalValueFlagPattern() string {
+	patterns := make([]string, 0, len(spec.globalFlags))
+	for _, f := range spec.globalFlags {
+		if f.takesValue {
+			patterns = append(patterns, flagPattern(f.name))
+		}
+	}
+	return strings.Join(patterns, "|")
+}
+
+// all command names and aliases.
+func (spec *completionSpec) commandNames() []string {
+	names := make([]string, 0, len(spec.commands)*2)
+	for _, cc := range spec.commands {
+		names = append(names, cc.names...)
+	}
+	return names
+}
+
+// the fixed words to complete for an arg kind. nil for kinds completed by the shell
+// itself (dirs, files) or not at all.
+func (spec *completionSpec) words(kind argKind, choices []string) []string {
+	switch kind {
+	case argCommand:
+		return spec.commandNames()
+	case argRepoName:
+		return spec.repos
+	case argSym:
+		return spec.syms
+	case argChoice:
+		return choices
+	}
+	return nil
+}
+
+// write the completion script for shell to w.
+func writeCompletion(w io.Writer, shell string, spec completionSpec) error {
+	switch shell {
+	case "bash":
+		_, err := io.WriteString(w, bashCompletion(&spec))
+		return err
+	case "zsh":
+		_, err := io.WriteString(w, zshCompletion(&spec))
+		return err
+	case "fish":
+		_, err := io.WriteString(w, fishCompletion(&spec))
+		return err
+	}
+	return fmt.Errorf("unsupported shell %q. expected bash, zsh or fish", shell)
+}
+
+// quote s as a single word for bash and zsh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quote s as a single word for fish.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+// "--sym|-sym". go flags accept 1 or 2 dashes.
+func flagPattern(name string) string {
+	return "--" + name + "|-" + name
+}
+
+func bashCompletion(spec *completionSpec) string {
+	fn := "_" + programName
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s. generated by: %s completion bash\n", programName, programName)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local cur prev cmd i=1\n")
+	b.WriteString("    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	b.WriteString("    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	// the command is the 1st word after the global flags and their values.
+	b.WriteString("    while [[ $i -lt $COMP_CWORD ]]; do\n")
+	b.WriteString("        case \"${COMP_WORDS[i]}\" in\n")
+	// bash splits --config=x into 3 words.
+	fmt.Fprintf(&b, "        %s) [[ \"${COMP_WORDS[i+1]}\" == = ]] && i=$((i+3)) || i=$((i+2)) ;;\n", spec.globalValueFlagPattern())
+	b.WriteString("        -*) i=$((i+1)) ;;\n")
+	b.WriteString("        *) cmd=\"${COMP_WORDS[i]}\"; break ;;\n")
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n")
+	b.WriteString("    if [[ -z \"$cmd\" ]]; then\n")
+	b.WriteString("        case \"$prev\" in\n")
+	for _, f := range spec.globalFlags {
+		if f.takesValue {
+			fmt.Fprintf(&b, "        %s) %s; return ;;\n", flagPattern(f.name), bashReply(spec, f.value, f.choices))
+		}
+	}
+	b.WriteString("        esac\n")
+	b.WriteString("        if [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(flagWords(spec.globalFlags), " ")))
+	b.WriteString("        else\n")
+	fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(spec.commandNames(), " ")))
+	b.WriteString("        fi\n")
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case \"$cmd\" in\n")
+	for _, cc := range spec.commands {
+		fmt.Fprintf(&b, "    %s)\n", strings.Join(cc.names, "|"))
+		flagNames := flagWords(cc.flags)
+		hasValueFlags := false
+		for _, f := range cc.flags {
+			hasValueFlags = hasValueFlags || f.takesValue
+		}
+		if hasValueFlags {
+			b.WriteString("        case \"$prev\" in\n")
+			for _, f := range cc.flags {
+				if f.takesValue {
+					fmt.Fprintf(&b, "        %s) %s; return ;;\n", flagPattern(f.name), bashReply(spec, f.value, f.choices))
+				}
+			}
+			b.WriteString("        esac\n")
+		}
+		b.WriteString("        if [[ \"$cur\" == -* ]]; then\n")
+		fmt.Fprintf(&b, "            COMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(flagNames, " ")))
+		b.WriteString("        else\n")
+		fmt.Fprintf(&b, "            %s\n", bashReply(spec, cc.args, cc.choices))
+		b.WriteString("        fi\n")
+		b.WriteString("        ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, programName)
+	return b.String()
+}
+
+// bash statement that fills COMPREPLY for an arg kind.
+func bashReply(spec *completionSpec, kind argKind, choices []string) string {
+	switch kind {
+	case argDir:
+		return `COMPREPLY=($(compgen -d -- "$cur"))`
+	case argFile:
+		return `COMPREPLY=($(compgen -f -- "$cur"))`
+	}
+	words := spec.words(kind, choices)
+	if words == nil {
+		return "COMPREPLY=()"
+	}
+	return fmt.Sprintf(`COMPREPLY=($(compgen -W %s -- "$cur"))`, shellQuote(strings.Join(words, " ")))
+}
+
+func zshCompletion(spec *completionSpec) string {
+	fn := "_" + programName
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n", programName)
+	fmt.Fprintf(&b, "# zsh completion for %s. generated by: %s completion zsh\n", programName, programName)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("    local cur=${words[CURRENT]} prev=${words[CURRENT-1]} cmd i=2\n")
+	// the command is the 1st word after the global flags and their values.
+	b.WriteString("    while (( i < CURRENT )); do\n")
+	b.WriteString("        case ${words[i]} in\n")
+	fmt.Fprintf(&b, "        %s) (( i += 2 )) ;;\n", spec.globalValueFlagPattern())
+	b.WriteString("        -*) (( i += 1 )) ;;\n")
+	b.WriteString("        *) cmd=${words[i]}; break ;;\n")
+	b.WriteString("        esac\n")
+	b.WriteString("    done\n")
+	b.WriteString("    if [[ -z $cmd ]]; then\n")
+	b.WriteString("        case $prev in\n")
+	for _, f := range spec.globalFlags {
+		if f.takesValue {
+			fmt.Fprintf(&b, "        %s) %s; return ;;\n", flagPattern(f.name), zshAction(spec, f.value, f.choices))
+		}
+	}
+	b.WriteString("        esac\n")
+	b.WriteString("        if [[ $cur == -* ]]; then\n")
+	fmt.Fprintf(&b, "            compadd -- %s\n", strings.Join(quoteAll(flagWords(spec.globalFlags)), " "))
+	b.WriteString("            return\n")
+	b.WriteString("        fi\n")
+	b.WriteString("        local -a cmds\n")
+	b.WriteString("        cmds=(\n")
+	for _, cc := range spec.commands {
+		for i, name := range cc.names {
+			summary := cc.summary
+			if i > 0 {
+				summary = "alias for " + cc.names[0]
+			}
+			fmt.Fprintf(&b, "            %s\n", shellQuote(name+":"+summary))
+		}
+	}
+	b.WriteString("        )\n")
+	b.WriteString("        _describe 'command' cmds\n")
+	b.WriteString("        return\n")
+	b.WriteString("    fi\n")
+	b.WriteString("    case $cmd in\n")
+	for _, cc := range spec.commands {
+		fmt.Fprintf(&b, "    %s)\n", strings.Join(cc.names, "|"))
+		flagNames := quoteAll(flagWords(cc.flags))
+		hasValueFlags := false
+		for _, f := range cc.flags {
+			hasValueFlags = hasValueFlags || f.takesValue
+		}
+		if hasValueFlags {
+			b.WriteString("        case $prev in\n")
+			for _, f := range cc.flags {
+				if f.takesValue {
+					fmt.Fprintf(&b, "        %s) %s; return ;;\n", flagPattern(f.name), zshAction(spec, f.value, f.choices))
+				}
+			}
+			b.WriteString("        esac\n")
+		}
+		b.WriteString("        if [[ $cur == -* ]]; then\n")
+		if len(flagNames) > 0 {
+			fmt.Fprintf(&b, "            compadd -- %s\n", strings.Join(flagNames, " "))
+		} else {
+			b.WriteString("            return 1\n")
+		}
+		b.WriteString("        else\n")
+		fmt.Fprintf(&b, "            %s\n", zshAction(spec, cc.args, cc.choices))
+		b.WriteString("        fi\n")
+		b.WriteString("        ;;\n")
+	}
+	b.WriteString("    esac\n")
+	b.WriteString("}\n")
+	// works both when autoloaded from $fpath and when sourced.
+	fmt.Fprintf(&b, "if [ \"$funcstack[1]\" = \"%s\" ]; then\n", fn)
+	fmt.Fprintf(&b, "    %s \"$@\"\n", fn)
+	b.WriteString("else\n")
+	fmt.Fprintf(&b, "    compdef %s %s\n", fn, programName)
+	b.WriteString("fi\n")
+	return b.String()
+}
+
+// zsh statement that completes an arg kind.
+func zshAction(spec *completionSpec, kind argKind, choices []string) string {
+	switch kind {
+	case argDir:
+		return "_files -/"
+	case argFile:
+		return "_files"
+	}
+	words := spec.words(kind, choices)
+	if len(words) == 0 {
+		return "return 1"
+	}
+	return "compadd -- " + strings.Join(quoteAll(words), " ")
+}
+
+// shellQuote each word.
+func quoteAll(words []string) []string {
+	quoted := make([]string, 0, len(words))
+	for _, w := range words {
+		quoted = append(quoted, shellQuote(w))
+	}
+	return quoted
+}
+
+func fishCompletion(spec *completionSpec) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s. generated by: %s completion fish\n", programName, programName)
+	// no file completion unless a command asks for it.
+	fmt.Fprintf(&b, "complete -c %s -f\n", programName)
+	// print the command, the 1st word after the global flags and their values. fish's
+	// __fish_use_subcommand would take the value of --config for the command.
+	cmdFn := "__" + programName + "_command"
+	fmt.Fprintf(&b, "function %s\n", cmdFn)
+	b.WriteString("    set -l words (commandline -opc)\n")
+	b.WriteString("    set -e words[1]\n")
+	b.WriteString("    while set -q words[1]\n")
+	b.WriteString("        switch $words[1]\n")
+	fmt.Fprintf(&b, "            case %s\n", strings.ReplaceAll(spec.globalValueFlagPattern(), "|", " "))
+	b.WriteString("                set -e words[1]\n")
+	b.WriteString("                set -q words[1]; and set -e words[1]\n")
+	b.WriteString("            case '-*'\n")
+	b.WriteString("                set -e words[1]\n")
+	b.WriteString("            case '*'\n")
+	b.WriteString("                echo $words[1]\n")
+	b.WriteString("                return 0\n")
+	b.WriteString("        end\n")
+	b.WriteString("    end\n")
+	b.WriteString("    return 1\n")
+	b.WriteString("end\n")
+	// true if the command is 1 of the names given.
+	usingFn := "__" + programName + "_using_command"
+	fmt.Fprintf(&b, "function %s\n", usingFn)
+	fmt.Fprintf(&b, "    set -l cmd (%s); or return 1\n", cmdFn)
+	b.WriteString("    contains -- $cmd $argv\n")
+	b.WriteString("end\n")
+
+	noCommand := fishQuote("not " + cmdFn + " >/dev/null")
+	for _, f := range spec.globalFlags {
+		writeFishFlag(&b, spec, noCommand, f)
+	}
+	for _, cc := range spec.commands {
+		for i, name := range cc.names {
+			summary := cc.summary
+			if i > 0 {
+				summary = "alias for " + cc.names[0]
+			}
+			fmt.Fprintf(&b, "complete -c %s -n %s -a %s -d %s\n",
+				programName, noCommand, fishQuote(name), fishQuote(summary))
+		}
+		cond := fishQuote(usingFn + " " + strings.Join(cc.names, " "))
+		for _, f := range cc.flags {
+			writeFishFlag(&b, spec, cond, f)
+		}
+		if cc.args != argNone {
+			fmt.Fprintf(&b, "complete -c %s -n %s %s\n", programName, cond, fishValue(spec, cc.args, cc.choices))
+		}
+	}
+	return b.String()
+}
+
+// write the fish completion of flag f, used when cond is true.
+func writeFishFlag(b *strings.Builder, spec *completionSpec, cond string, f completionFlag) {
+	opt := "-l"
+	if len(f.name) == 1 {
+		opt = "-s" // -v
+	}
+	fmt.Fprintf(b, "complete -c %s -n %s %s %s", programName, cond, opt, f.name)
+	if f.takesValue {
+		b.WriteString(" " + fishValue(spec, f.value, f.choices))
+	}
+	fmt.Fprintf(b, " -d %s\n", fishQuote(f.usage))
+}
+
+// fish options that complete an arg kind.
+func fishValue(spec *completionSpec, kind argKind, choices []string) string {
+	switch kind {
+	case argDir:
+		return "-x -a '(__fish_complete_directories)'"
+	case argFile:
+		return "-r -F"
+	}
+	return "-x -a " + fishQuote(strings.Join(spec.words(kind, choices), " "))
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+func testCompletionRepos() []config.GitRepo {
+	return []config.GitRepo{
+		{Name: "paredit", Remotes: []config.Remote{{Sym: "mine"}, {Sym: "upstream"}}},
+		{Name: "magit-delta", Remotes: []config.Remote{{Sym: "upstream"}, {Sym: "upstreamOrig"}}},
+	}
+}
+
+func TestNewCompletionSpec(t *testing.T) {
+	spec := newCompletionSpec(testCompletionRepos())
+	if strings.Join(spec.repos, " ") != "magit-delta paredit" {
+		t.Fatalf("got: %v. wanted sorted repo names", spec.repos)
+	}
+	if strings.Join(spec.syms, " ") != "mine upstream upstreamOrig" {
+		t.Fatalf("got: %v. wanted distinct sorted syms", spec.syms)
+	}
+	found := false
+	for _, cc := range spec.commands {
+		if cc.names[0] != "fetch" {
+			continue
+		}
+		found = true
+		for _, f := range cc.flags {
+			if f.name == "sym" && (!f.takesValue || f.value != argSym) {
+				t.Fatalf("got: %v. wanted --sym to complete syms", f)
+			}
+			if f.name == "all-remotes" && f.takesValue {
+				t.Fatalf("got: %v. wanted --all-remotes as a bool flag", f)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("fetch command missing from completion spec")
+	}
+	for _, cc := range spec.commands {
+		for _, f := range cc.flags {
+			if f.value == argChoice && len(f.choices) == 0 {
+				t.Errorf("%s --%s completes a choice without choices", cc.names[0], f.name)
+			}
+		}
+	}
+}
+
+// the generated scripts include the words and are valid syntax for each shell.
+// the syntax check is skipped for shells not installed.
+func TestCompletionScripts(t *testing.T) {
+	spec := newCompletionSpec(testCompletionRepos())
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var buf bytes.Buffer
+		if err := writeCompletion(&buf, shell, spec); err != nil {
+			t.Fatalf("err during test: %v", err)
+		}
+		script := buf.String()
+		for _, word := range []string{"init3Shallow", "upstreamOrig", "all-remotes", "yolo-root"} {
+			if !strings.Contains(script, word) {
+				t.Fatalf("%s script missing %s", shell, word)
+			}
+		}
+		if _, err := exec.LookPath(shell); err != nil {
+			continue
+		}
+		cmd := exec.Command(shell, "-n") // #nosec G204
+		cmd.Stdin = strings.NewReader(script)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%s script has a syntax error: %v %s", shell, err, out)
+		}
+	}
+
+	if err := writeCompletion(&bytes.Buffer{}, "powershell", spec); err == nil {
+		t.Fatalf("wanted an error for unsupported shell")
+	}
+}
+
+// the bash script completes after global flags and their values. ie
+// "gitFetchHelper --config x.jsonc fetch --sym <tab>". skipped if bash isn't installed.
+func TestBashCompletionGlobalFlags(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	var buf bytes.Buffer
+	if err := writeCompletion(&buf, "bash", newCompletionSpec(testCompletionRepos())); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line string // the words typed, the last is the one completed
+		want string
+	}{
+		{"gitFetchHelper fetchU", "fetchUpstream"},
+		{"gitFetchHelper --jobs 4 fetchU", "fetchUpstream"},
+		{"gitFetchHelper --config = x.jsonc -v fetchU", "fetchUpstream"},
+		{"gitFetchHelper --co", "--config"},
+		{"gitFetchHelper --timeout 2m -v", "-v"},
+		{"gitFetchHelper --config x.jsonc fetch --sym up", "upstream upstreamOrig"},
+		{"gitFetchHelper -v --output json fetch --all", "--all-remotes"},
+		// the value of a global flag is not a command.
+		{"gitFetchHelper --merge-sym fetch", ""},
+		// enum and sym flag values.
+		{"gitFetchHelper --output j", "json"},
+		{"gitFetchHelper --merge-sym m", "mine"},
+		{"gitFetchHelper cloneYoloRepos --mode s", "shallow"},
+		{"gitFetchHelper -v config convert --to y", "yaml"},
+		{"gitFetchHelper diff --sym upstreamO", "upstreamOrig"},
+	}
+	for _, tt := range tests {
+		words := strings.Fields(tt.line)
+		script := buf.String() + "COMP_WORDS=(" + strings.Join(quoteAll(words), " ") + ")\n" +
+			"COMP_CWORD=" + strconv.Itoa(len(words)-1) + "\n" +
+			"_gitFetchHelper\necho \"${COMPREPLY[*]}\"\n"
+		cmd := exec.Command("bash", "-c", script)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s: %v %s", tt.line, err, out)
+		}
+		if got := strings.TrimSpace(string(out)); got != tt.want {
+			t.Errorf("%q completes to %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
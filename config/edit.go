@@ -0,0 +1,227 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Editing of repos.jsonc from the command line. Edits are done on the text so the
+// comments and hand formatting survive.
+
+// get the array of GitRepo entries in the parsed config.
+// The legacy config is the array itself, the object form has it in "repos".
+func reposArray(path string, root *jsoncNode) (*jsoncNode, error) {
+	if root.kind == jsoncArray {
+		return root, nil
+	}
+	if arr := root.member("repos"); arr != nil && arr.kind == jsoncArray {
+		return arr, nil
+	}
+	return nil, fmt.Errorf("%s: expected an array of repos or an object with a \"repos\" array", path)
+}
+
+// find the index of the entry named name in the repos array. -1 if not found.
+func findRepoEntry(arr *jsoncNode, name string) int {
+	for i, elem := range arr.elems {
+		if n := elem.member("name"); n != nil && n.kind == jsoncString && n.str == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// read and parse a config file for editing. ie repos.jsonc or an included file.
+func readConfigForEdit(path string) ([]byte, *jsoncNode, *jsoncNode, error) {
+	if format := FormatOf(path); format != FormatJSONC {
+		return nil, nil, nil, fmt.Errorf("%s: editing is only done on jsonc files, not %s. "+
+			"edit it by hand or switch with config convert --to jsonc", path, format)
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	root, err := parseJsonc(src)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	arr, err := reposArray(path, root)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return src, root, arr, nil
+}
+
+// write the edited config. The text is decoded first so a bad edit never replaces a
+// good config. Written to a temp file then renamed so a crash can't leave half a file.
+func writeConfig(path string, src []byte) error {
+	if _, err := Decode(src); err != nil {
+		return fmt.Errorf("edited config does not parse, not saved: %w", err)
+	}
+
+	perm := os.FileMode(0o644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		perm = info.Mode().Perm() // keep the permissions of the existing file
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, src, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// format a GitRepo entry in the hand written style of repos.jsonc. The entry is
+// expected to start at column 1 (after a 1 space indent).
+func FormatRepoEntry(repo *GitRepo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "{\"name\": %s,\n", JSONString(repo.Name))
+	fmt.Fprintf(&b, "  \"folder\": %s,\n", JSONString(repo.Folder))
+	b.WriteString("  \"remotes\": [")
+	for i, rem := range repo.Remotes {
+		if i > 0 {
+			b.WriteString(",\n              ")
+		}
+		fmt.Fprintf(&b, "{\"sym\": %s,\n", JSONString(rem.Sym))
+		fmt.Fprintf(&b, "               \"url\": %s,\n", JSONString(rem.URL))
+		fmt.Fprintf(&b, "               \"alias\": %s}", JSONString(rem.Alias))
+	}
+	b.WriteString("],\n")
+	fmt.Fprintf(&b, "  \"remoteDefault\": %s,\n", JSONString(repo.RemoteDefaultSym))
+	fmt.Fprintf(&b, "  \"branchMain\": %s,\n", JSONString(repo.BranchMain))
+	fmt.Fprintf(&b, "  \"branchUse\": %s,\n", JSONString(repo.BranchUse))
+	if repo.CloneMode != "" {
+		fmt.Fprintf(&b, "  \"isYolo\": %t,\n", repo.IsYolo)
+		fmt.Fprintf(&b, "  \"cloneMode\": %s\n", JSONString(repo.CloneMode))
+	} else {
+		fmt.Fprintf(&b, "  \"isYolo\": %t\n", repo.IsYolo)
+	}
+	b.WriteString(" }")
+	return b.String()
+}
+
+// append a new GitRepo entry to the config file path. Fails if the name is already used.
+func AddRepo(path string, repo *GitRepo) error {
+	src, _, arr, err := readConfigForEdit(path)
+	if err != nil {
+		return err
+	}
+	if findRepoEntry(arr, repo.Name) >= 0 {
+		return fmt.Errorf("%s is already in %s", repo.Name, path)
+	}
+	// line up with the existing entries. FormatRepoEntry expects a 1 space indent.
+	indent := elemIndent(src, arr)
+	entry := strings.ReplaceAll(FormatRepoEntry(repo), "\n", "\n"+strings.TrimPrefix(indent, " "))
+	src = jsoncAppendElem(src, arr, entry, indent)
+	return writeConfig(path, src)
+}
+
+// the indent of the 1st element of arr. 1 space if it's empty or not on its own line.
+func elemIndent(src []byte, arr *jsoncNode) string {
+	if len(arr.elems) == 0 {
+		return " "
+	}
+	start := arr.elems[0].start
+	lineStart := bytes.LastIndexByte(src[:start], '\n') + 1
+	indent := src[lineStart:start]
+	if len(indent) == 0 || len(bytes.TrimSpace(indent)) > 0 {
+		return " "
+	}
+	return string(indent)
+}
+
+// delete the GitRepo entry named name from the config file path.
+func RemoveRepo(path, name string) error {
+	src, _, arr, err := readConfigForEdit(path)
+	if err != nil {
+		return err
+	}
+	i := findRepoEntry(arr, name)
+	if i < 0 {
+		return fmt.Errorf("%s is not in %s", name, path)
+	}
+	src = jsoncRemoveElem(src, arr, i)
+	return writeConfig(path, src)
+}
+
+// set fields of the GitRepo entry named name in the config file path. fields maps json keys to
+// already formatted json values. ie {"isYolo": "true"}. Keys are set in sorted order so
+// added members always come out the same.
+func SetRepoFields(path, name string, fields map[string]string) error {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	src, _, arr, err := readConfigForEdit(path)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		i := findRepoEntry(arr, name)
+		if i < 0 {
+			return fmt.Errorf("%s is not in %s", name, path)
+		}
+		src = jsoncSetMember(src, arr.elems[i], key, fields[key])
+		// offsets moved. re-parse for the next key.
+		root, err := parseJsonc(src)
+		if err != nil {
+			return err
+		}
+		if arr, err = reposArray(path, root); err != nil {
+			return err
+		}
+	}
+	return writeConfig(path, src)
+}
+
+// get the folder name a git clone of url would create. ie "magit" for
+// https://github.com/magit/magit.git
+func CloneDirName(url string) string {
+	url = strings.TrimRight(url, "/")
+	url = strings.TrimSuffix(url, ".git")
+	// scp style urls: git@github.com:magit/magit
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	return url
+}
+
+// build a new GitRepo from command line options. The default folder is in the yoloRoot or
+// submoduleRoot of settings.
+// The "mine" fork gets the "origin" alias as it's the one cloned. Without a fork the
+// upstream is cloned directly so it's "origin" instead.
+func NewGitRepo(settings Settings, name, upstreamURL, mineURL, branch, folder string, isYolo bool) GitRepo {
+	repo := GitRepo{
+		Name:       name,
+		Folder:     folder,
+		BranchMain: branch,
+		BranchUse:  branch,
+		IsYolo:     isYolo,
+	}
+	upstreamAlias := "origin"
+	repo.RemoteDefaultSym = "upstream"
+	if mineURL != "" {
+		repo.Remotes = append(repo.Remotes, Remote{Sym: "mine", URL: mineURL, Alias: "origin"})
+		upstreamAlias = "upstream"
+		repo.RemoteDefaultSym = "mine"
+	}
+	repo.Remotes = append(repo.Remotes, Remote{Sym: "upstream", URL: upstreamURL, Alias: upstreamAlias})
+
+	if repo.Folder == "" {
+		root := settings.SubmoduleRoot
+		if isYolo {
+			root = settings.YoloRoot
+		}
+		// folder is named after what's cloned. my fork if there is one.
+		cloneURL := upstreamURL
+		if mineURL != "" {
+			cloneURL = mineURL
+		}
+		repo.Folder = filepath.ToSlash(filepath.Join(root, CloneDirName(cloneURL)))
+	}
+	return repo
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+func TestCloneDirName(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/magit/magit.git":    "magit",
+		"https://github.com/miketz/nov.el":      "nov.el",
+		"https://github.com/miketz/nov.el/":     "nov.el",
+		"git@github.com:jorgenschaefer/elpy":    "elpy",
+		"https://paredit.org/paredit.git":       "paredit",
+		"https://codeberg.org/WammKD/Emacs-Klo": "Emacs-Klo",
+	}
+	for url, want := range tests {
+		if got := CloneDirName(url); got != want {
+			t.Fatalf("%s got: %s. wanted %s", url, got, want)
+		}
+	}
+}
+
+func TestNewGitRepo(t *testing.T) {
+	repo := NewGitRepo(Settings{}.ForOS("linux"), "nov", "https://depp.brause.cc/nov.el.git", "https://github.com/miketz/nov.el", "master", "", true)
+	if repo.Folder != "~/.emacs.d/notElpaYolo/nov.el" {
+		t.Fatalf("got: %s. wanted folder named after my fork", repo.Folder)
+	}
+	if repo.RemoteDefaultSym != "mine" {
+		t.Fatalf("got: %s. wanted mine as default", repo.RemoteDefaultSym)
+	}
+	mine, _ := repo.RemoteMine()
+	upstream, _ := repo.RemoteUpstream()
+	if mine.Alias != "origin" || upstream.Alias != "upstream" {
+		t.Fatalf("got: %v %v. wanted origin and upstream aliases", mine, upstream)
+	}
+
+	// no fork. the upstream is cloned directly so it's origin.
+	repo = NewGitRepo(Settings{}.ForOS("linux"), "magit-delta", "https://github.com/dandavison/magit-delta", "", "main", "", false)
+	upstream, _ = repo.RemoteUpstream()
+	if repo.RemoteDefaultSym != "upstream" || upstream.Alias != "origin" || len(repo.Remotes) != 1 {
+		t.Fatalf("got: %v. wanted 1 upstream origin remote", repo)
+	}
+	if repo.Folder != "~/.emacs.d/notElpa/magit-delta" {
+		t.Fatalf("got: %s. wanted a notElpa folder", repo.Folder)
+	}
+}
+
+// a formatted entry decodes back to the same GitRepo.
+func TestFormatRepoEntry(t *testing.T) {
+	want := NewGitRepo(Settings{}.ForOS("linux"), "nov", "https://depp.brause.cc/nov.el.git?a=1&b=2", "https://github.com/miketz/nov.el", "master", "", true)
+	text := "[" + FormatRepoEntry(&want) + "]"
+	if !strings.Contains(text, `a=1&b=2`) {
+		t.Fatalf("got: %s. wanted & left unescaped", text)
+	}
+	dec, err := jsonc.NewDecoder(bufio.NewReader(strings.NewReader(text)))
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	var got []GitRepo
+	if err = dec.Decode(&got); err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != want.Name || got[0].Folder != want.Folder ||
+		len(got[0].Remotes) != 2 || got[0].Remotes[1] != want.Remotes[1] || got[0].IsYolo != want.IsYolo {
+		t.Fatalf("got: %v. wanted %v", got, want)
+	}
+}
+
+func TestAddRepoToObjectConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "repos.jsonc")
+	src := `{
+  "settings": {"yoloRoot": "~/yolo"},
+  "repos": [
+    {"name": "a",
+      "folder": "~/yolo/a",
+      "isYolo": true
+    },
+  ]
+}
+`
+	if err := os.WriteFile(configPath, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewGitRepo(Settings{}.ForOS("linux"), "b", "https://github.com/x/b", "", "master", "~/yolo/b", true)
+	if err := AddRepo(configPath, &repo); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the new entry starts at the indent of the existing one.
+	if !strings.Contains(string(out), "    },\n    {\"name\": \"b\",\n     \"folder\": \"~/yolo/b\",\n") {
+		t.Errorf("entry not lined up:\n%s", out)
+	}
+	cfg, err := Decode(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Repos) != 2 || cfg.Repos[1].Name != "b" || cfg.Settings.YoloRoot != "~/yolo" {
+		t.Errorf("got %+v", cfg)
+	}
+}
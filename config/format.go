@@ -0,0 +1,425 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/komkom/jsonc/jsonc"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// Config files in YAML and TOML for those who hate JSON even with comments. They are
+// converted to json when loaded, so the json tags of GitRepo and Remote are the only
+// mapping. Edits by add, remove, etc. only work on jsonc files.
+
+// the format of a config file.
+type Format string
+
+const (
+	FormatJSONC Format = "jsonc"
+	FormatYAML  Format = "yaml"
+	FormatTOML  Format = "toml"
+)
+
+// the formats config convert can write.
+var Formats = []Format{FormatJSONC, FormatYAML, FormatTOML}
+
+// config files looked for when --config isn't given, in order.
+var DefaultPaths = []string{"./repos.jsonc", "./repos.yaml", "./repos.yml", "./repos.toml"}
+
+// the 1st of the default config files that exists. repos.jsonc if none do.
+func FindDefault() string {
+	for _, path := range DefaultPaths {
+		if found, _ := exists(path); found {
+			return path
+		}
+	}
+	return DefaultPaths[0]
+}
+
+// the format of a config file by its extension. jsonc if the extension is unknown.
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSONC
+}
+
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == s {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown config format %q. expected jsonc, yaml or toml", s)
+}
+
+// convert the text of a config file to json. jsonc is returned as is, the jsonc decoder
+// takes it.
+func toJSON(format Format, src []byte) ([]byte, error) {
+	var v any
+	switch format {
+	case FormatYAML:
+		if err := yaml.Unmarshal(src, &v); err != nil {
+			return nil, err
+		}
+	case FormatTOML:
+		var table map[string]any
+		if err := toml.Unmarshal(src, &table); err != nil {
+			return nil, err
+		}
+		v = table
+	default:
+		return src, nil
+	}
+	return json.Marshal(v)
+}
+
+// decode a config file to plain json values. ie map[string]any, []any, string, bool.
+func decodeGeneric(format Format, src []byte) (any, error) {
+	src, err := toJSON(format, src)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := jsonc.NewDecoder(bufio.NewReader(bytes.NewReader(src)))
+	if err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err = dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	var v any
+	jsonDec := json.NewDecoder(bytes.NewReader(raw))
+	jsonDec.UseNumber() // keep ints as written
+	err = jsonDec.Decode(&v)
+	return v, err
+}
+
+// convert the text of a config file from 1 format to another. Keys are written in the
+// order of the struct fields, ie name then folder. Comments are not carried over.
+func Convert(w io.Writer, from, to Format, src []byte) error {
+	v, err := decodeGeneric(from, src)
+	if err != nil {
+		return err
+	}
+	rootType := reflect.TypeOf(Config{})
+	if _, isArray := v.([]any); isArray {
+		rootType = reflect.TypeOf([]GitRepo{})
+	}
+	ordered := orderValue(v, rootType)
+
+	switch to {
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err = enc.Encode(yamlNode(ordered)); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatTOML:
+		root, isObject := ordered.(orderedObject)
+		if !isObject {
+			// the legacy array of repos. TOML needs a table at the top.
+			root = orderedObject{{"repos", ordered}}
+		}
+		var b strings.Builder
+		writeTOMLTable(&b, nil, root)
+		_, err = io.WriteString(w, strings.TrimLeft(b.String(), newLine))
+		return err
+	}
+	var b strings.Builder
+	writeJSONValue(&b, ordered, "")
+	b.WriteString(newLine)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// a key and value of an orderedObject.
+type orderedMember struct {
+	key string
+	val any
+}
+
+// an object with its keys in order. values are orderedObject, []any or scalars.
+type orderedObject []orderedMember
+
+// order the keys of the objects in v by the json fields of t. Keys t doesn't know
+// follow in sorted order. t may be nil when unknown.
+func orderValue(v any, t reflect.Type) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		order := jsonFieldOrder(t)
+		sort.SliceStable(keys, func(i, j int) bool {
+			oi, iKnown := order[keys[i]]
+			oj, jKnown := order[keys[j]]
+			if iKnown != jKnown {
+				return iKnown
+			}
+			if iKnown {
+				return oi < oj
+			}
+			return keys[i] < keys[j]
+		})
+		obj := make(orderedObject, 0, len(keys))
+		for _, k := range keys {
+			obj = append(obj, orderedMember{k, orderValue(v[k], memberType(t, k))})
+		}
+		return obj
+	case []any:
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+		arr := make([]any, len(v))
+		for i := range v {
+			arr[i] = orderValue(v[i], elemType)
+		}
+		return arr
+	}
+	return v
+}
+
+// the position of each json field of struct t, by json name.
+func jsonFieldOrder(t reflect.Type) map[string]int {
+	order := make(map[string]int)
+	if t == nil || t.Kind() != reflect.Struct {
+		return order
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" {
+			order[name] = i
+		}
+	}
+	return order
+}
+
+// the json name of a struct field. "" if not in json.
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	}
+	return name
+}
+
+// the type of the member key of an object of type t. nil if unknown.
+func memberType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if jsonName(t.Field(i)) == key {
+				return t.Field(i).Type
+			}
+		}
+	}
+	return nil
+}
+
+// format a scalar as json. Also valid as a TOML value.
+func jsonScalar(v any) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("%q", fmt.Sprint(v))
+	}
+	return strings.TrimRight(b.String(), newLine)
+}
+
+// write v as json. Arrays of scalars stay on 1 line.
+func writeJSONValue(b *strings.Builder, v any, indent string) {
+	switch v := v.(type) {
+	case orderedObject:
+		if len(v) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		b.WriteString("{\n")
+		for i, m := range v {
+			fmt.Fprintf(b, "%s  %s: ", indent, jsonScalar(m.key))
+			writeJSONValue(b, m.val, indent+"  ")
+			if i < len(v)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString(newLine)
+		}
+		b.WriteString(indent + "}")
+	case []any:
+		if !hasObjects(v) {
+			b.WriteString("[")
+			for i := range v {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				writeJSONValue(b, v[i], indent)
+			}
+			b.WriteString("]")
+			return
+		}
+		b.WriteString("[\n")
+		for i := range v {
+			b.WriteString(indent + "  ")
+			writeJSONValue(b, v[i], indent+"  ")
+			if i < len(v)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString(newLine)
+		}
+		b.WriteString(indent + "]")
+	default:
+		b.WriteString(jsonScalar(v))
+	}
+}
+
+// true if any element of arr is an object.
+func hasObjects(arr []any) bool {
+	for _, elem := range arr {
+		if _, ok := elem.(orderedObject); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// build a yaml node of v so the encoder keeps the key order.
+func yamlNode(v any) *yaml.Node {
+	switch v := v.(type) {
+	case orderedObject:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, m := range v {
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: m.key}, yamlNode(m.val))
+		}
+		return node
+	case []any:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		if !hasObjects(v) {
+			node.Style = yaml.FlowStyle // ie os: [windows]
+		}
+		for i := range v {
+			node.Content = append(node.Content, yamlNode(v[i]))
+		}
+		return node
+	case json.Number:
+		tag := "!!int"
+		if _, err := v.Int64(); err != nil {
+			tag = "!!float" // ie 1.5
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(v); err != nil {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: fmt.Sprint(v)}
+	}
+	return node
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func tomlKey(key string) string {
+	if tomlBareKey.MatchString(key) {
+		return key
+	}
+	return jsonScalar(key)
+}
+
+// write the members of the table at path. Plain values 1st, then the sub tables and
+// arrays of tables, as TOML requires.
+func writeTOMLTable(b *strings.Builder, path []string, obj orderedObject) {
+	for _, m := range obj {
+		if isTOMLTable(m.val) || m.val == nil {
+			continue // null has no TOML form. leaving it out is the same.
+		}
+		fmt.Fprintf(b, "%s = %s\n", tomlKey(m.key), tomlInline(m.val))
+	}
+	for _, m := range obj {
+		subPath := append(append([]string{}, path...), tomlKey(m.key))
+		switch val := m.val.(type) {
+		case orderedObject:
+			// a table of only tables is implied by theirs. ie [settings.os.windows]
+			if len(val) == 0 || slices.ContainsFunc(val, func(m orderedMember) bool { return !isTOMLTable(m.val) }) {
+				fmt.Fprintf(b, "\n[%s]\n", strings.Join(subPath, "."))
+			}
+			writeTOMLTable(b, subPath, val)
+		case []any:
+			if !isTOMLTable(val) {
+				continue
+			}
+			for _, elem := range val {
+				fmt.Fprintf(b, "\n[[%s]]\n", strings.Join(subPath, "."))
+				writeTOMLTable(b, subPath, elem.(orderedObject))
+			}
+		}
+	}
+}
+
+// true if v is written as a [table] or [[array of tables]] instead of key = value.
+func isTOMLTable(v any) bool {
+	switch v := v.(type) {
+	case orderedObject:
+		return true
+	case []any:
+		if len(v) == 0 {
+			return false
+		}
+		for _, elem := range v {
+			if _, ok := elem.(orderedObject); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// format v as an inline TOML value.
+func tomlInline(v any) string {
+	switch v := v.(type) {
+	case orderedObject:
+		parts := make([]string, 0, len(v))
+		for _, m := range v {
+			if m.val != nil {
+				parts = append(parts, tomlKey(m.key)+" = "+tomlInline(m.val))
+			}
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, elem := range v {
+			parts = append(parts, tomlInline(elem))
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return jsonScalar(v)
+}
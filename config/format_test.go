@@ -0,0 +1,123 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]Format{
+		"repos.jsonc":       FormatJSONC,
+		"repos.json":        FormatJSONC,
+		"dir/repos.yaml":    FormatYAML,
+		"repos.local.YML":   FormatYAML,
+		"repos.toml":        FormatTOML,
+		"repos.local.jsonc": FormatJSONC,
+	}
+	for path, want := range tests {
+		if got := FormatOf(path); got != want {
+			t.Errorf("FormatOf(%s) = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestConvertConfig(t *testing.T) {
+	src := `{
+  // comments are dropped
+  "repos": [
+    {"isYolo": true, "name": "a", "folder": "~/a", "os": ["windows"],
+     "remotes": [{"url": "https://x.org/a?x=1&y=2", "sym": "upstream", "alias": "origin"}]},
+    {"name": "b", "branchUse": "mine"}, // partial, ie an override
+  ],
+  "settings": {"jobs": 4, "os": {"windows": {"home": "D:/home"}}},
+  "disable": ["c"]
+}`
+	want, err := decodeGeneric(FormatJSONC, []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range Formats {
+		var out bytes.Buffer
+		if err = Convert(&out, FormatJSONC, format, []byte(src)); err != nil {
+			t.Fatalf("to %s: %v", format, err)
+		}
+		got, err := decodeGeneric(format, out.Bytes())
+		if err != nil {
+			t.Fatalf("%s output doesn't parse: %v\n%s", format, err, out.String())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s round trip = %v, want %v\n%s", format, got, want, out.String())
+		}
+		// keys in struct order, settings before repos and name before folder.
+		text := out.String()
+		if !(strings.Index(text, "jobs") < strings.Index(text, "name") &&
+			strings.Index(text, "name") < strings.Index(text, "folder") &&
+			strings.Index(text, "folder") < strings.Index(text, "isYolo")) {
+			t.Errorf("%s keys out of order:\n%s", format, text)
+		}
+	}
+
+	// the legacy array is wrapped in a repos table for TOML.
+	var out bytes.Buffer
+	if err = Convert(&out, FormatJSONC, FormatTOML, []byte(`[{"name": "a"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "[[repos]]\nname = \"a\"\n" {
+		t.Errorf("got %q", out.String())
+	}
+}
+
+func TestConvertYamlNumbers(t *testing.T) {
+	var out bytes.Buffer
+	src := `{"settings": {"jobs": 4, "x": 1.5}}`
+	if err := Convert(&out, FormatJSONC, FormatYAML, []byte(src)); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]map[string]any
+	if err := yaml.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("%v\n%s", err, out.String())
+	}
+	want := map[string]any{"jobs": 4, "x": 1.5}
+	if !reflect.DeepEqual(got["settings"], want) {
+		t.Errorf("got %#v, want %#v\n%s", got["settings"], want, out.String())
+	}
+}
+
+func TestDecodeConfigRawFormats(t *testing.T) {
+	yamlSrc := `
+settings:
+  yoloRoot: ~/yolo
+repos:
+  - name: a
+    folder: ~/a
+    isYolo: true
+`
+	tomlSrc := `
+[settings]
+yoloRoot = "~/yolo"
+
+[[repos]]
+name = "a"
+folder = "~/a"
+isYolo = true
+`
+	for format, src := range map[Format]string{FormatYAML: yamlSrc, FormatTOML: tomlSrc} {
+		cfg, repos, err := decodeRaw(format, []byte(src))
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if cfg.Settings.YoloRoot != "~/yolo" || len(repos) != 1 || !strings.Contains(string(repos[0]), `"isYolo":true`) {
+			t.Errorf("%s: got %+v %s", format, cfg, repos)
+		}
+	}
+}
+
+func TestEditOnlyJsonc(t *testing.T) {
+	if _, _, _, err := readConfigForEdit("repos.yaml"); err == nil || !strings.Contains(err.Error(), "only done on jsonc") {
+		t.Errorf("got %v. wanted yaml edits refused", err)
+	}
+}
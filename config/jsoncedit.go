@@ -0,0 +1,365 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Minimal jsonc parser that remembers where each value is in the source text.
+// Used to edit repos.jsonc in place (add, remove, change entries) without
+// destroying the hand written comments and formatting. Decoding into structs is
+// still done by the jsonc package, this is only for surgical text edits.
+
+type jsoncKind int
+
+const (
+	jsoncObject jsoncKind = iota + 1
+	jsoncArray
+	jsoncString
+	jsoncNumber
+	jsoncBool
+	jsoncNull
+)
+
+// a value in a jsonc document.
+type jsoncNode struct {
+	kind jsoncKind
+	// byte offsets of the value in the source. src[start:end] is the value text.
+	start, end int
+	// members of an object in source order.
+	members []jsoncMember
+	// elements of an array in source order.
+	elems []*jsoncNode
+	// decoded value of a string.
+	str string
+}
+
+// a "key": value pair of an object.
+type jsoncMember struct {
+	key string
+	// byte offset of the opening quote of the key.
+	keyStart int
+	value    *jsoncNode
+}
+
+// get the value of an object member by key. nil if not found or not an object.
+func (n *jsoncNode) member(key string) *jsoncNode {
+	if n == nil || n.kind != jsoncObject {
+		return nil
+	}
+	for i := range n.members {
+		if n.members[i].key == key {
+			return n.members[i].value
+		}
+	}
+	return nil
+}
+
+// parse a jsonc document.
+func parseJsonc(src []byte) (*jsoncNode, error) {
+	p := jsoncParser{src: src}
+	node, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(src) {
+		return nil, p.errorf("unexpected text after the top level value")
+	}
+	return node, nil
+}
+
+type jsoncParser struct {
+	src []byte
+	pos int
+}
+
+func (p *jsoncParser) errorf(format string, args ...any) error {
+	line := 1 + bytes.Count(p.src[:p.pos], []byte("\n"))
+	return fmt.Errorf("jsonc line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skip white space and comments.
+func (p *jsoncParser) skipSpace() {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.pos++
+		case bytes.HasPrefix(p.src[p.pos:], []byte("//")):
+			end := bytes.IndexByte(p.src[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += end + 1
+			}
+		case bytes.HasPrefix(p.src[p.pos:], []byte("/*")):
+			end := bytes.Index(p.src[p.pos+2:], []byte("*/"))
+			if end < 0 {
+				p.pos = len(p.src)
+			} else {
+				p.pos += 2 + end + 2
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *jsoncParser) value() (*jsoncNode, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"':
+		return p.string()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.literal(jsoncNumber)
+	case c == 't' || c == 'f':
+		return p.literal(jsoncBool)
+	case c == 'n':
+		return p.literal(jsoncNull)
+	default:
+		return nil, p.errorf("unexpected character %q", c)
+	}
+}
+
+func (p *jsoncParser) object() (*jsoncNode, error) {
+	node := &jsoncNode{kind: jsoncObject, start: p.pos}
+	p.pos++ // {
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated object")
+		}
+		if p.src[p.pos] == '}' {
+			p.pos++
+			node.end = p.pos
+			return node, nil
+		}
+		if p.src[p.pos] != '"' {
+			return nil, p.errorf("expected an object key")
+		}
+		key, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != ':' {
+			return nil, p.errorf("expected : after key %q", key.str)
+		}
+		p.pos++
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		node.members = append(node.members, jsoncMember{key: key.str, keyStart: key.start, value: val})
+		if err = p.comma('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *jsoncParser) array() (*jsoncNode, error) {
+	node := &jsoncNode{kind: jsoncArray, start: p.pos}
+	p.pos++ // [
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.src[p.pos] == ']' {
+			p.pos++
+			node.end = p.pos
+			return node, nil
+		}
+		val, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		node.elems = append(node.elems, val)
+		if err = p.comma(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// consume the comma after a member/element. trailing commas are allowed.
+// A missing comma is tolerated like the jsonc decoder does. It happens in repos.jsonc
+// when a comma ends up inside a comment: "branchUse": "mine" // ignore .elc files,
+func (p *jsoncParser) comma(closer byte) error {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return p.errorf("unexpected end of input, expected , or %c", closer)
+	}
+	if p.src[p.pos] == ',' {
+		p.pos++
+	}
+	return nil
+}
+
+func (p *jsoncParser) string() (*jsoncNode, error) {
+	start := p.pos
+	p.pos++ // opening quote
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			node := &jsoncNode{kind: jsoncString, start: start, end: p.pos}
+			if err := json.Unmarshal(p.src[start:p.pos], &node.str); err != nil {
+				return nil, p.errorf("bad string: %v", err)
+			}
+			return node, nil
+		default:
+			p.pos++
+		}
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// number, true, false, null.
+func (p *jsoncParser) literal(kind jsoncKind) (*jsoncNode, error) {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		isLiteralChar := (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '+' || c == '.' || c == 'E'
+		if !isLiteralChar {
+			break
+		}
+		p.pos++
+	}
+	text := string(p.src[start:p.pos])
+	switch kind {
+	case jsoncBool:
+		if text != "true" && text != "false" {
+			return nil, p.errorf("bad literal %q", text)
+		}
+	case jsoncNull:
+		if text != "null" {
+			return nil, p.errorf("bad literal %q", text)
+		}
+	}
+	return &jsoncNode{kind: kind, start: start, end: p.pos}, nil
+}
+
+// encode s as a json string. Unlike json.Marshal, & < > are not escaped so URLs stay readable.
+func JSONString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s) // encoding a string can't fail
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// replace src[start:end] with text. returns a new slice.
+func splice(src []byte, start, end int, text string) []byte {
+	out := make([]byte, 0, len(src)-(end-start)+len(text))
+	out = append(out, src[:start]...)
+	out = append(out, text...)
+	out = append(out, src[end:]...)
+	return out
+}
+
+// replace the text of a value.
+func jsoncReplaceValue(src []byte, node *jsoncNode, text string) []byte {
+	return splice(src, node.start, node.end, text)
+}
+
+// set the value of member key in object obj. valueText is the already formatted value.
+// A missing member is added after the last member, lined up with it.
+func jsoncSetMember(src []byte, obj *jsoncNode, key, valueText string) []byte {
+	for _, m := range obj.members {
+		if m.key == key {
+			return jsoncReplaceValue(src, m.value, valueText)
+		}
+	}
+	memberText := JSONString(key) + ": " + valueText
+	if len(obj.members) == 0 {
+		return splice(src, obj.start+1, obj.start+1, memberText)
+	}
+	last := obj.members[len(obj.members)-1]
+	lineStart := bytes.LastIndexByte(src[:last.keyStart], '\n') + 1
+	indent := strings.Repeat(" ", last.keyStart-lineStart)
+	return splice(src, last.value.end, last.value.end, ",\n"+indent+memberText)
+}
+
+// append an element to the end of array arr. elemText is the already formatted element,
+// it's placed on a new line indented by indent. A trailing comma after the last element
+// is kept so the file style doesn't change.
+func jsoncAppendElem(src []byte, arr *jsoncNode, elemText, indent string) []byte {
+	if len(arr.elems) == 0 {
+		inner := src[arr.start+1 : arr.end-1]
+		if len(bytes.TrimSpace(inner)) == 0 {
+			// only white space. keep the ] where it was: [\n ] => [\n elem\n ]
+			closeIndent := ""
+			if nl := bytes.LastIndexByte(inner, '\n'); nl >= 0 {
+				closeIndent = string(inner[nl+1:])
+			}
+			return splice(src, arr.start+1, arr.end-1, "\n"+indent+elemText+"\n"+closeIndent)
+		}
+		return splice(src, arr.start+1, arr.start+1, "\n"+indent+elemText+"\n")
+	}
+	last := arr.elems[len(arr.elems)-1]
+	p := jsoncParser{src: src, pos: last.end}
+	p.skipSpace()
+	hasTrailingComma := p.pos < len(src) && src[p.pos] == ','
+	if hasTrailingComma {
+		// go past a comment on the rest of the line: 1, // one
+		insertAt := p.pos + 1
+		if nl := bytes.IndexByte(src[insertAt:], '\n'); nl >= 0 {
+			rest := bytes.TrimSpace(src[insertAt : insertAt+nl])
+			if len(rest) == 0 || bytes.HasPrefix(rest, []byte("//")) {
+				insertAt += nl
+			}
+		}
+		return splice(src, insertAt, insertAt, "\n"+indent+elemText+",")
+	}
+	return splice(src, last.end, last.end, ",\n"+indent+elemText)
+}
+
+// remove element i of array arr. Lines that only held the element (and its comma) are
+// removed entirely so no blank lines are left behind.
+func jsoncRemoveElem(src []byte, arr *jsoncNode, i int) []byte {
+	elem := arr.elems[i]
+	start, end := elem.start, elem.end
+
+	// take the comma after the element if there is 1.
+	prevComma := -1
+	p := jsoncParser{src: src, pos: end}
+	p.skipSpace()
+	if p.pos < len(src) && src[p.pos] == ',' {
+		end = p.pos + 1
+	} else if i > 0 {
+		// last element without a trailing comma. the previous element's comma must go
+		// instead. removed on its own so a comment after it survives.
+		prev := jsoncParser{src: src, pos: arr.elems[i-1].end}
+		prev.skipSpace()
+		if prev.pos < start && src[prev.pos] == ',' {
+			prevComma = prev.pos
+		}
+	}
+
+	// widen to whole lines if nothing else is on them.
+	lineStart := bytes.LastIndexByte(src[:start], '\n') + 1
+	if len(bytes.TrimSpace(src[lineStart:start])) == 0 {
+		start = lineStart
+		if nl := bytes.IndexByte(src[end:], '\n'); nl >= 0 && len(bytes.TrimSpace(src[end:end+nl])) == 0 {
+			end += nl + 1
+		}
+	}
+	src = splice(src, start, end, "")
+	if prevComma >= 0 {
+		src = splice(src, prevComma, prevComma+1, "")
+	}
+	return src
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+// the parser must understand the real config, comments and all.
+func TestParseJsoncReposConfig(t *testing.T) {
+	src, err := os.ReadFile("../repos.jsonc")
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	root, err := parseJsonc(src)
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	cfg, err := Load("../repos.jsonc")
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	repos := cfg.Repos
+	if len(root.elems) != len(repos) {
+		t.Fatalf("got: %d entries. wanted %d", len(root.elems), len(repos))
+	}
+	for i, elem := range root.elems {
+		if got := elem.member("name").str; got != repos[i].Name {
+			t.Fatalf("got: %s. wanted %s", got, repos[i].Name)
+		}
+	}
+}
+
+func TestJsoncAppendElem(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		// trailing comma style is kept
+		{"[\n 1, // one\n]", "[\n 1, // one\n 2,\n]"},
+		{"[\n 1\n]", "[\n 1,\n 2\n]"},
+		{"[]", "[\n 2\n]"},
+		{"[\n ]", "[\n 2\n ]"},
+	}
+	for _, tt := range tests {
+		root, err := parseJsonc([]byte(tt.src))
+		if err != nil {
+			t.Fatalf("err during test: %v", err)
+		}
+		got := string(jsoncAppendElem([]byte(tt.src), root, "2", " "))
+		if got != tt.want {
+			t.Fatalf("got: %q. wanted %q", got, tt.want)
+		}
+	}
+}
+
+func TestJsoncRemoveElem(t *testing.T) {
+	src := "[\n // first\n {\"a\": 1},\n {\"b\": 2}, // keep me\n {\"c\": 3}\n]"
+	tests := []struct {
+		i    int
+		want string
+	}{
+		{0, "[\n // first\n {\"b\": 2}, // keep me\n {\"c\": 3}\n]"},
+		{1, "[\n // first\n {\"a\": 1},\n // keep me\n {\"c\": 3}\n]"},
+		{2, "[\n // first\n {\"a\": 1},\n {\"b\": 2} // keep me\n]"},
+	}
+	for _, tt := range tests {
+		root, err := parseJsonc([]byte(src))
+		if err != nil {
+			t.Fatalf("err during test: %v", err)
+		}
+		got := string(jsoncRemoveElem([]byte(src), root, tt.i))
+		if got != tt.want {
+			t.Fatalf("remove %d got: %q. wanted %q", tt.i, got, tt.want)
+		}
+		if _, err = parseJsonc([]byte(got)); err != nil {
+			t.Fatalf("remove %d left invalid jsonc: %v", tt.i, err)
+		}
+	}
+}
+
+func TestJsoncReplaceValue(t *testing.T) {
+	src := `{"branchMain": "master", // upstream renamed it?
+ "isYolo": false}`
+	root, err := parseJsonc([]byte(src))
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	got := string(jsoncReplaceValue([]byte(src), root.member("branchMain"), JSONString("main")))
+	want := `{"branchMain": "main", // upstream renamed it?
+ "isYolo": false}`
+	if got != want {
+		t.Fatalf("got: %q. wanted %q", got, want)
+	}
+}
+
+func TestJsoncSetMember(t *testing.T) {
+	src := `{"name": "magit",
+  "folder": "~/.emacs.d/notElpa/magit", // submodule
+  "branchUse": "mine"
+ }`
+	root, err := parseJsonc([]byte(src))
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	got := jsoncSetMember([]byte(src), root, "folder", JSONString("~/.emacs.d/notElpaYolo/magit"))
+	root, err = parseJsonc(got)
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	got = jsoncSetMember(got, root, "isYolo", "true")
+	want := `{"name": "magit",
+  "folder": "~/.emacs.d/notElpaYolo/magit", // submodule
+  "branchUse": "mine",
+  "isYolo": true
+ }`
+	if string(got) != want {
+		t.Fatalf("got: %q. wanted %q", got, want)
+	}
+}
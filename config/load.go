@@ -0,0 +1,242 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Loading of the config with its includes and the per machine overlay. Some packages
+// are only used on the work laptop, some only on MS Windows. Those go in an included
+// file or in repos.local.jsonc next to repos.jsonc, which is not checked in. Each file
+// may be jsonc, yaml or toml by its extension.
+
+// read the config file, the files it includes, then its overlay if there is one.
+// Later files win: a repo with the name of an earlier repo overrides the fields it
+// sets, settings override the earlier settings, and disabled repos are dropped.
+func Load(path string) (Config, error) {
+	l := configLoader{loading: make(map[string]bool)}
+	if err := l.load(path); err != nil {
+		return Config{}, err
+	}
+	overlay := overlayPath(path)
+	if found, _ := exists(overlay); found {
+		if err := l.load(overlay); err != nil {
+			return Config{}, err
+		}
+	}
+	return l.cfg, nil
+}
+
+// path of the per machine overlay of a config file. ie repos.local.jsonc
+func overlayPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + ".local" + ext
+}
+
+// merges config files into 1 Config in load order.
+type configLoader struct {
+	cfg Config
+	// files currently being loaded. an include of 1 of them is a cycle.
+	loading map[string]bool
+}
+
+func (l *configLoader) load(path string) error {
+	path = filepath.Clean(path)
+	if l.loading[path] {
+		return fmt.Errorf("config file %s includes itself", path)
+	}
+	l.loading[path] = true
+	defer delete(l.loading, path)
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("opening config file: %w", err)
+	}
+	cfg, rawRepos, err := decodeRaw(FormatOf(path), src)
+	if err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	for _, inc := range cfg.Include {
+		incPath, err := includePath(path, inc)
+		if err != nil {
+			return fmt.Errorf("%s: include %s: %w", path, inc, err)
+		}
+		if err = l.load(incPath); err != nil {
+			return err
+		}
+	}
+	l.cfg.Settings = l.cfg.Settings.Layer(cfg.Settings)
+	for _, raw := range rawRepos {
+		if err = l.addRepo(path, raw); err != nil {
+			return fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+	for _, name := range cfg.Disable {
+		i := slices.IndexFunc(l.cfg.Repos, func(r GitRepo) bool { return r.Name == name })
+		if i < 0 {
+			return fmt.Errorf("%s: can't disable %s. no repo by that name", path, name)
+		}
+		l.cfg.Repos = slices.Delete(l.cfg.Repos, i, i+1)
+	}
+	return nil
+}
+
+// add the repo in raw json, or override the fields of the repo with the same name.
+// NOTE: overriding a list like remotes replaces the whole list.
+func (l *configLoader) addRepo(path string, raw json.RawMessage) error {
+	var named struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil {
+		return err
+	}
+	if named.Name == "" {
+		return errors.New("repo without a name")
+	}
+	i := slices.IndexFunc(l.cfg.Repos, func(r GitRepo) bool { return r.Name == named.Name })
+	if i >= 0 {
+		l.cfg.Repos[i].OverriddenIn = append(l.cfg.Repos[i].OverriddenIn, path)
+		return json.Unmarshal(raw, &l.cfg.Repos[i])
+	}
+	repo := GitRepo{Source: path}
+	if err := json.Unmarshal(raw, &repo); err != nil {
+		return err
+	}
+	l.cfg.Repos = append(l.cfg.Repos, repo)
+	return nil
+}
+
+// get the file the json field key of repo comes from. The last override that sets it
+// wins, same as Load. Source if no override sets it.
+func FieldSource(repo *GitRepo, key string) (string, error) {
+	for i := len(repo.OverriddenIn) - 1; i >= 0; i-- {
+		path := repo.OverriddenIn[i]
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		_, rawRepos, err := decodeRaw(FormatOf(path), src)
+		if err != nil {
+			return "", fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+		for _, raw := range rawRepos {
+			var fields map[string]json.RawMessage
+			if err = json.Unmarshal(raw, &fields); err != nil {
+				return "", fmt.Errorf("parsing config file %s: %w", path, err)
+			}
+			var name string
+			if err = json.Unmarshal(fields["name"], &name); err != nil || name != repo.Name {
+				continue
+			}
+			if _, ok := fields[key]; ok {
+				return path, nil
+			}
+		}
+	}
+	return repo.Source, nil
+}
+
+// resolve an include of the config file from. Relative paths are relative to from's
+// folder. ~ is the user's home dir, the home setting isn't known until all files load.
+func includePath(from, inc string) (string, error) {
+	inc = ExpandEnv(inc)
+	if strings.HasPrefix(inc, "~") {
+		home, err := HomeDir("")
+		if err != nil {
+			return "", err
+		}
+		inc = home + inc[1:]
+	}
+	if !filepath.IsAbs(inc) {
+		inc = filepath.Join(filepath.Dir(from), inc)
+	}
+	return inc, nil
+}
+
+// true if the repo is used on the OS goos and the computer host. Hostnames are not case
+// sensitive.
+func (r *GitRepo) ActiveOn(goos, host string) bool {
+	if len(r.OS) > 0 && !slices.Contains(r.OS, goos) {
+		return false
+	}
+	if len(r.Hosts) > 0 && !slices.ContainsFunc(r.Hosts, func(h string) bool { return strings.EqualFold(h, host) }) {
+		return false
+	}
+	return true
+}
+
+// split repos into the ones used on goos and host, and the rest.
+func SplitActive(repos []GitRepo, goos, host string) ([]GitRepo, []GitRepo) {
+	active := make([]GitRepo, 0, len(repos))
+	inactive := make([]GitRepo, 0, 4)
+	for _, repo := range repos {
+		if repo.ActiveOn(goos, host) {
+			active = append(active, repo)
+		} else {
+			inactive = append(inactive, repo)
+		}
+	}
+	return active, inactive
+}
+
+// print the effective config: the settings resolved for this machine and the repos
+// after the includes and the overlay are merged, ie without the repos inactive here.
+// Each repo is commented with the files it comes from. The output is a valid config file.
+func Dump(w io.Writer, settings Settings, repos []GitRepo) error {
+	settingsJSON, err := marshalIndent(settings, "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "{\n  \"settings\": %s,\n  \"repos\": [\n", settingsJSON)
+	for i := range repos {
+		repo := &repos[i]
+		fmt.Fprintf(w, "    // from %s", repo.Source)
+		if len(repo.OverriddenIn) > 0 {
+			fmt.Fprintf(w, ", overridden in %s", strings.Join(repo.OverriddenIn, ", "))
+		}
+		repoJSON, err := marshalIndent(repo, "    ")
+		if err != nil {
+			return err
+		}
+		sep := ","
+		if i == len(repos)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(w, "\n    %s%s\n", repoJSON, sep)
+	}
+	_, err = fmt.Fprint(w, "  ]\n}\n")
+	return err
+}
+
+// like json.MarshalIndent with a 2 space indent, but URLs keep their & and the
+// trailing new line is dropped.
+func marshalIndent(v any, prefix string) ([]byte, error) {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent(prefix, "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), newLine), nil
+}
+
+// returns true if file or directory exists.
+func exists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// write files relative to dir. map of path to content.
+func writeConfigFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadConfigIncludeAndOverlay(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"repos.jsonc": `{
+  "settings": {"jobs": 4},
+  "include": ["work/repos.jsonc"],
+  "repos": [
+    {"name": "a", "folder": "~/a", "branchMain": "master", "branchUse": "master"},
+    {"name": "b", "folder": "~/b"},
+  ]
+}`,
+		// the legacy form works as an include too.
+		"work/repos.jsonc": `[ {"name": "w", "folder": "~/w", "isYolo": true} ]`,
+		"repos.local.jsonc": `{
+  "settings": {"timeout": "1m"},
+  "repos": [
+    {"name": "a", "branchUse": "mine"}, // override
+    {"name": "c", "folder": "~/c"},     // add
+  ],
+  "disable": ["b"]
+}`,
+	})
+	cfg, err := Load(filepath.Join(dir, "repos.jsonc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(cfg.Repos))
+	for _, r := range cfg.Repos {
+		names = append(names, r.Name)
+	}
+	if strings.Join(names, " ") != "w a c" {
+		t.Fatalf("got repos %v. wanted the include 1st, b disabled and c added", names)
+	}
+	a := cfg.Repos[1]
+	if a.Folder != "~/a" || a.BranchMain != "master" || a.BranchUse != "mine" {
+		t.Errorf("got %+v. wanted only branchUse overridden", a)
+	}
+	if a.Source != filepath.Join(dir, "repos.jsonc") || len(a.OverriddenIn) != 1 ||
+		a.OverriddenIn[0] != filepath.Join(dir, "repos.local.jsonc") {
+		t.Errorf("got source %s overridden in %v", a.Source, a.OverriddenIn)
+	}
+	if w := cfg.Repos[0]; w.Source != filepath.Join(dir, "work", "repos.jsonc") || !w.IsYolo {
+		t.Errorf("got %+v", w)
+	}
+	if cfg.Settings.Jobs != 4 || cfg.Settings.Timeout != "1m" {
+		t.Errorf("got settings %+v. wanted both files' settings", cfg.Settings)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		files map[string]string
+		want  string
+	}{
+		{map[string]string{"repos.jsonc": `{"include": ["repos.jsonc"], "repos": []}`}, "includes itself"},
+		{map[string]string{"repos.jsonc": `{"include": ["nope.jsonc"], "repos": []}`}, "opening config file"},
+		{map[string]string{"repos.jsonc": `[]`, "repos.local.jsonc": `{"disable": ["x"]}`}, "can't disable x"},
+		{map[string]string{"repos.jsonc": `[{"folder": "~/a"}]`}, "repo without a name"},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeConfigFiles(t, dir, tt.files)
+		_, err := Load(filepath.Join(dir, "repos.jsonc"))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: got error %v. wanted %q", tt.files, err, tt.want)
+		}
+	}
+}
+
+func TestDumpConfig(t *testing.T) {
+	settings := Settings{}.ForOS("linux")
+	repos := []GitRepo{
+		{Name: "a", Folder: "~/a", Source: "repos.jsonc", OverriddenIn: []string{"repos.local.jsonc"},
+			Remotes: []Remote{{Sym: "upstream", URL: "https://x.org/a?x=1&y=2", Alias: "origin"}}},
+		{Name: "b", Folder: "~/b", Source: "repos.jsonc"},
+	}
+	var out bytes.Buffer
+	if err := Dump(&out, settings, repos); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "// from repos.jsonc, overridden in repos.local.jsonc\n") ||
+		!strings.Contains(out.String(), "a?x=1&y=2") {
+		t.Errorf("got:\n%s", out.String())
+	}
+	// the dump is a config file itself.
+	cfg, err := Decode(out.Bytes())
+	if err != nil {
+		t.Fatalf("dump doesn't parse: %v\n%s", err, out.String())
+	}
+	if len(cfg.Repos) != 2 || cfg.Repos[0].Remotes[0] != repos[0].Remotes[0] || cfg.Settings.MergeSym != settings.MergeSym {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestActiveOn(t *testing.T) {
+	tests := []struct {
+		repo GitRepo
+		want bool
+	}{
+		{GitRepo{}, true},
+		{GitRepo{OS: []string{"linux", "darwin"}}, true},
+		{GitRepo{OS: []string{"windows"}}, false},
+		{GitRepo{Hosts: []string{"Work-Laptop"}}, true},
+		{GitRepo{Hosts: []string{"home-pc"}}, false},
+		{GitRepo{OS: []string{"linux"}, Hosts: []string{"home-pc"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.repo.ActiveOn("linux", "work-laptop"); got != tt.want {
+			t.Errorf("%+v ActiveOn(linux, work-laptop) = %t, want %t", tt.repo, got, tt.want)
+		}
+	}
+
+	repos := []GitRepo{{Name: "a"}, {Name: "w", OS: []string{"windows"}}, {Name: "b"}}
+	active, inactive := SplitActive(repos, "linux", "work-laptop")
+	if len(active) != 2 || active[1].Name != "b" || len(inactive) != 1 || inactive[0].Name != "w" {
+		t.Errorf("got active %v, inactive %v", active, inactive)
+	}
+}
+
+func TestFieldSource(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFiles(t, dir, map[string]string{
+		"repos.jsonc": `{
+  "include": ["work/repos.yaml"],
+  "repos": [ {"name": "a", "folder": "~/a", "branchMain": "master", "branchUse": "master"} ]
+}`,
+		"work/repos.yaml":   "repos:\n  - name: a\n    branchUse: mine\n",
+		"repos.local.jsonc": `{ "repos": [ {"name": "b"}, {"name": "a", "branchMain": "main"} ] }`,
+	})
+	cfg, err := Load(filepath.Join(dir, "repos.jsonc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := cfg.Repos[0]
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"folder", "repos.jsonc"},
+		{"branchUse", "repos.jsonc"}, // the include loads before, so the main file wins
+		{"branchMain", "repos.local.jsonc"},
+	}
+	for _, tt := range tests {
+		got, err := FieldSource(&a, tt.key)
+		if err != nil || got != filepath.Join(dir, tt.want) {
+			t.Errorf("FieldSource(%s) = %s %v, want %s", tt.key, got, err, tt.want)
+		}
+	}
+}
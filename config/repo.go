@@ -0,0 +1,129 @@
+// Package config is the repos.jsonc of gitFetchHelper: the repos and settings, loading
+// them with includes and the per machine overlay, the yaml/toml formats, the JSON
+// Schema and editing the file in place.
+package config
+
+import (
+	"fmt"
+)
+
+// new line character.
+var newLine = "\n"
+
+// Info about the server side remote.
+type Remote struct {
+	// A special tag to identify the meaning of the Remote.
+	// Alias is not enough to convey meaning as it's often "origin" by default after a git clone.
+	// "upstream" represents the original or canonical repo of the project.
+	// "mine" is my fork.
+	Sym string `json:"sym"`
+	// Git remote URL
+	URL string `json:"url"`
+	// The alias used by git to reference the remote. May match the Sym value
+	// but not always. For example my fork will usually have an alias of "origin" with a
+	// Sym of "mine"
+	Alias string `json:"alias"`
+}
+
+// GitRepo holds info about a git repo. In this case my .emacs.d/notElpa submodules.
+type GitRepo struct {
+	// Simple short name of the project. In the case of Emacs packages make this
+	// the feature symbol used by (require 'feature).
+	Name string `json:"name"`
+	// Top level root folder of the project.
+	Folder string `json:"folder"`
+	// List of remotes. Usually will be 2 remotes. It's expected that most repos will have
+	// a remote of Sym "mine" and "upstream", however there can be unlimited remotes. The
+	// Sym field is used to identify the special remotes in the slice.
+	Remotes []Remote `json:"remotes"`
+	// The remote we are tracking against. In my case this is usually my fork specified via sym "mine".
+	RemoteDefaultSym string `json:"remoteDefault"`
+	// The branch we are interested in following for this Emacs package.
+	// It may be a "develop" branch if we are interested in the bleeding edge.
+	BranchMain string `json:"branchMain"`
+	// The branch we will use. Usually the same as BranchMain. But sometimes I
+	// will use a custom branch derived from BranchMain for small modifications,
+	// even if it's a minor change like adding to .gitignore.
+	BranchUse string `json:"branchUse"`
+	// not a git submodule
+	IsYolo bool `json:"isYolo"`
+	// how cloneYoloRepos clones the repo: "full" (the default), "shallow" or "blobless".
+	// blobless keeps the full history for merges but downloads far less.
+	CloneMode string `json:"cloneMode,omitempty"`
+	// only use the repo on these OSes, by GOOS. ie ["windows"]. default: every OS.
+	OS []string `json:"os,omitempty"`
+	// only use the repo on these computers, by hostname. ie ["work-laptop"]. default: every host.
+	Hosts []string `json:"hosts,omitempty"`
+	// the config file the entry is in. ie an include or repos.local.jsonc. Edits like
+	// remove go to this file. set when loaded.
+	Source string `json:"-"`
+	// the config files that override fields of the entry. set when loaded.
+	OverriddenIn []string `json:"-"`
+}
+
+// get the "upstream" remote for the git repo.
+func (r *GitRepo) RemoteUpstream() (Remote, error) {
+	return r.GetRemoteBySym("upstream")
+}
+
+// get the "mine" remote for the git repo. This is usually my fork or my own project.
+func (r *GitRepo) RemoteMine() (Remote, error) {
+	return r.GetRemoteBySym("mine")
+}
+
+// get the "default" remote specified by "RemoteDefaultSym" for the git repo.
+// Sometimes this may be the upstream, but usually my fork or my own project.
+func (r *GitRepo) RemoteDefault() (Remote, error) {
+	return r.GetRemoteBySym(r.RemoteDefaultSym)
+}
+
+// get the remote based on symbol "sym".
+// sym is a semantic meaning for the remote separate from it's alias name.
+func (r *GitRepo) GetRemoteBySym(sym string) (Remote, error) {
+	for _, rem := range r.Remotes {
+		if rem.Sym == sym {
+			return rem, nil
+		}
+	}
+	// return Remote{}, fmt.Errorf("no " + sym + " remote configured for " + r.Name + " in repos.jsonc")
+	return Remote{}, fmt.Errorf("no %s remote configured for %s in repos.jsonc", sym, r.Name)
+}
+
+// find the repo named name. false if not found.
+func FindRepo(repos []GitRepo, name string) (GitRepo, bool) {
+	for i := 0; i < len(repos); i++ {
+		if repos[i].Name == name {
+			return repos[i], true
+		}
+	}
+	return GitRepo{}, false
+}
+
+// how a yolo repo is cloned.
+type CloneMode string
+
+const (
+	// full history and every blob. the default.
+	CloneFull CloneMode = "full"
+	// only the tip of each branch. fast, but merges/rebases need a fetch --unshallow later.
+	CloneShallow CloneMode = "shallow"
+	// partial clone with --filter=blob:none. full history for merges, but file contents
+	// are only downloaded when checked out.
+	CloneBlobless CloneMode = "blobless"
+)
+
+// the clone modes in the order shown by help.
+var CloneModes = []CloneMode{CloneFull, CloneShallow, CloneBlobless}
+
+// parse a clone mode from the command line or repos.jsonc. "" means full.
+func ParseCloneMode(s string) (CloneMode, error) {
+	if s == "" {
+		return CloneFull, nil
+	}
+	for _, m := range CloneModes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown clone mode %q. expected full, shallow or blobless", s)
+}
@@ -0,0 +1,15 @@
+package config
+
+import "testing"
+
+func TestParseCloneMode(t *testing.T) {
+	if mode, err := ParseCloneMode(""); err != nil || mode != CloneFull {
+		t.Errorf(`ParseCloneMode("") = %v %v, want full`, mode, err)
+	}
+	if mode, err := ParseCloneMode("blobless"); err != nil || mode != CloneBlobless {
+		t.Errorf(`ParseCloneMode("blobless") = %v %v, want blobless`, mode, err)
+	}
+	if _, err := ParseCloneMode("sparse"); err == nil {
+		t.Errorf(`ParseCloneMode("sparse") should fail`)
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"embed"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// JSON Schema of the config so editors can validate and complete repos.jsonc. Built
+// from the json tags of the config structs. The descriptions are their doc comments,
+// read from the source embedded below so they can't drift from the code.
+
+// the source files declaring the config structs.
+//
+//go:embed repo.go settings.go
+var configStructSources embed.FS
+
+// the structs in the schema. Config is the top level, or the legacy array of GitRepo.
+var schemaTypes = []reflect.Type{
+	reflect.TypeOf(Config{}),
+	reflect.TypeOf(Settings{}),
+	reflect.TypeOf(GitRepo{}),
+	reflect.TypeOf(Remote{}),
+}
+
+// allowed values of string fields, by "Type.Field".
+func schemaEnums() map[string][]string {
+	modes := make([]string, 0, len(CloneModes))
+	for _, m := range CloneModes {
+		modes = append(modes, string(m))
+	}
+	return map[string][]string{
+		"GitRepo.CloneMode": modes,
+		"Settings.Output":   {"text", "json"},
+	}
+}
+
+// write the JSON Schema of the config.
+func WriteSchema(w io.Writer) error {
+	docs, err := structDocs()
+	if err != nil {
+		return err
+	}
+	defs := make(orderedObject, 0, len(schemaTypes))
+	for _, t := range schemaTypes {
+		defs = append(defs, orderedMember{t.Name(), structSchema(t, docs)})
+	}
+	root := orderedObject{
+		{"$schema", "https://json-schema.org/draft/2020-12/schema"},
+		{"title", "gitFetchHelper config"},
+		{"description", "the repos and settings. an object, or the legacy array of repos."},
+		{"anyOf", []any{
+			orderedObject{{"$ref", "#/$defs/Config"}},
+			orderedObject{{"type", "array"}, {"items", orderedObject{{"$ref", "#/$defs/GitRepo"}}}},
+		}},
+		{"$defs", defs},
+	}
+	var b strings.Builder
+	writeJSONValue(&b, root, "")
+	b.WriteString(newLine)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// the schema of struct t. Extra properties are errors so typos are caught, except
+// "$schema" in Config to point editors at the schema.
+func structSchema(t reflect.Type, docs map[string]string) orderedObject {
+	enums := schemaEnums()
+	props := make(orderedObject, 0, t.NumField()+1)
+	if t == reflect.TypeOf(Config{}) {
+		props = append(props, orderedMember{"$schema", orderedObject{
+			{"description", "path or URL of this schema. for editors."},
+			{"type", "string"},
+		}})
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := jsonName(f)
+		if name == "" {
+			continue
+		}
+		key := t.Name() + "." + f.Name
+		prop := orderedObject{}
+		if doc := docs[key]; doc != "" {
+			prop = append(prop, orderedMember{"description", doc})
+		}
+		prop = append(prop, typeSchema(f.Type)...)
+		if values, ok := enums[key]; ok {
+			enum := make([]any, len(values))
+			for i, v := range values {
+				enum[i] = v
+			}
+			prop = append(prop, orderedMember{"enum", enum})
+		}
+		props = append(props, orderedMember{name, prop})
+	}
+	schema := orderedObject{}
+	if doc := docs[t.Name()]; doc != "" {
+		schema = append(schema, orderedMember{"description", doc})
+	}
+	return append(schema,
+		orderedMember{"type", "object"},
+		orderedMember{"properties", props},
+		orderedMember{"additionalProperties", false})
+}
+
+// the schema members of a field's type.
+func typeSchema(t reflect.Type) orderedObject {
+	switch t.Kind() {
+	case reflect.String:
+		return orderedObject{{"type", "string"}}
+	case reflect.Bool:
+		return orderedObject{{"type", "boolean"}}
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return orderedObject{{"type", "integer"}}
+	case reflect.Slice:
+		return orderedObject{{"type", "array"}, {"items", typeSchema(t.Elem())}}
+	case reflect.Map:
+		return orderedObject{{"type", "object"}, {"additionalProperties", typeSchema(t.Elem())}}
+	case reflect.Struct:
+		return orderedObject{{"$ref", "#/$defs/" + t.Name()}}
+	}
+	return orderedObject{} // anything
+}
+
+// the doc comments of the config structs and their fields, by "Type" and "Type.Field".
+func structDocs() (map[string]string, error) {
+	docs := make(map[string]string)
+	files, err := configStructSources.ReadDir(".")
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		src, err := configStructSources.ReadFile(file.Name())
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, file.Name(), src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				st, ok := ts.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				typeDoc := ts.Doc
+				if typeDoc == nil && len(gen.Specs) == 1 {
+					typeDoc = gen.Doc
+				}
+				docs[ts.Name.Name] = docText(typeDoc)
+				for _, field := range st.Fields.List {
+					doc := field.Doc
+					if doc == nil {
+						doc = field.Comment
+					}
+					for _, name := range field.Names {
+						docs[ts.Name.Name+"."+name.Name] = docText(doc)
+					}
+				}
+			}
+		}
+	}
+	return docs, nil
+}
+
+// a comment as 1 line of text.
+func docText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
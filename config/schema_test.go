@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+)
+
+var updateSchema = flag.Bool("update", false, "rewrite repos.schema.json from the config structs")
+
+// the committed schema, at the top of the repo next to repos.jsonc.
+const schemaFile = "../repos.schema.json"
+
+// repos.schema.json must match the structs. After changing them run:
+//
+//	go test ./config -run TestSchemaInSync -update
+func TestSchemaInSync(t *testing.T) {
+	var got bytes.Buffer
+	if err := WriteSchema(&got); err != nil {
+		t.Fatal(err)
+	}
+	if *updateSchema {
+		if err := os.WriteFile(schemaFile, got.Bytes(), 0o644); err != nil { // #nosec G306
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(schemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Fatalf("repos.schema.json is out of date with the config structs. " +
+			"regenerate it with: go test ./config -run TestSchemaInSync -update")
+	}
+}
+
+func TestSchemaFields(t *testing.T) {
+	var out bytes.Buffer
+	if err := WriteSchema(&out); err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Defs map[string]struct {
+			Properties map[string]struct {
+				Description string   `json:"description"`
+				Type        string   `json:"type"`
+				Enum        []string `json:"enum"`
+			} `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	repo := schema.Defs["GitRepo"].Properties
+	if repo["name"].Description == "" || repo["isYolo"].Type != "boolean" || repo["remotes"].Type != "array" {
+		t.Errorf("got GitRepo properties %+v", repo)
+	}
+	if _, ok := repo["Source"]; ok {
+		t.Errorf("fields not in json are in the schema")
+	}
+	if modes := repo["cloneMode"].Enum; len(modes) != len(CloneModes) {
+		t.Errorf("got cloneMode enum %v", modes)
+	}
+	if schema.Defs["Remote"].Properties["sym"].Description == "" {
+		t.Errorf("Remote fields missing descriptions")
+	}
+}
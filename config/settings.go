@@ -0,0 +1,264 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/komkom/jsonc/jsonc"
+)
+
+// Config is the top level of repos.jsonc. The legacy form of the file is just the
+// repos array, without settings.
+type Config struct {
+	Settings Settings `json:"settings"`
+	// other config files loaded before this one. ie packages only used at work.
+	// Relative paths are relative to this file.
+	Include []string `json:"include,omitempty"`
+	// repos added. A repo with the name of a repo in an earlier file overrides the
+	// fields it sets instead.
+	Repos []GitRepo `json:"repos"`
+	// names of repos from earlier files to leave out. ie in repos.local.jsonc.
+	Disable []string `json:"disable,omitempty"`
+}
+
+// Settings are the machine specific paths and the defaults of the commands, so 1
+// repos.jsonc works on every computer. Paths may use ~ and environment variables like
+// ${XDG_CONFIG_HOME} or ${XDG_CONFIG_HOME:-~/.config}. Most can be overridden by the
+// global CLI flags.
+type Settings struct {
+	// the folder ~ expands to. Here ~ is the user's home dir. ie "~/AppData/Local" on MS
+	// Windows where my emacs config lives. default: the user's home dir.
+	Home string `json:"home,omitempty"`
+	// the superproject holding the submodules. default: ~/.emacs.d
+	EmacsDir string `json:"emacsDir,omitempty"`
+	// where yolo repos are cloned. default: <emacsDir>/notElpaYolo
+	YoloRoot string `json:"yoloRoot,omitempty"`
+	// where submodules are added. default: <emacsDir>/notElpa
+	SubmoduleRoot string `json:"submoduleRoot,omitempty"`
+	// max # of git commands running at once. default: 0, no limit.
+	Jobs int `json:"jobs,omitempty"`
+	// git commands running longer than this are killed. a duration like "2m" or "30s".
+	// default: no timeout.
+	Timeout string `json:"timeout,omitempty"`
+	// BranchMain of repos that don't set it. BranchUse defaults to BranchMain.
+	// default: master
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+	// Sym of the remote mergeMine merges. default: mine
+	MergeSym string `json:"mergeSym,omitempty"`
+	// report format: "text" or "json". default: text
+	Output string `json:"output,omitempty"`
+	// overrides of the settings above for 1 OS, by GOOS. ie "windows", "linux", "darwin".
+	OS map[string]Settings `json:"os,omitempty"`
+}
+
+// resolve the settings for goos. Later layers win: the defaults, the general settings,
+// the OS override, then the overrides (ie from CLI flags). The result has every field
+// set except OS, Jobs and Timeout which default to no limit.
+func (s Settings) ForOS(goos string, overrides ...Settings) Settings {
+	resolved := Settings{
+		DefaultBranch: "master",
+		MergeSym:      "mine",
+		Output:        "text",
+	}
+	if goos == "windows" {
+		// NOTE: this is a custom adjustment for my personal emacs config location
+		// on MS Windows.
+		resolved.Home = "~/AppData/Local"
+	}
+	layers := append([]Settings{s, s.OS[goos]}, overrides...)
+	for _, layer := range layers {
+		resolved = resolved.merge(layer)
+	}
+	if resolved.EmacsDir == "" {
+		resolved.EmacsDir = "~/.emacs.d"
+	}
+	emacsDir := strings.TrimRight(resolved.EmacsDir, "/")
+	if resolved.YoloRoot == "" {
+		resolved.YoloRoot = emacsDir + "/notElpaYolo"
+	}
+	if resolved.SubmoduleRoot == "" {
+		resolved.SubmoduleRoot = emacsDir + "/notElpa"
+	}
+	return resolved
+}
+
+// the fields set in layer replace the ones in s. OS is not merged.
+func (s Settings) merge(layer Settings) Settings {
+	if layer.Home != "" {
+		s.Home = layer.Home
+	}
+	if layer.EmacsDir != "" {
+		s.EmacsDir = layer.EmacsDir
+	}
+	if layer.YoloRoot != "" {
+		s.YoloRoot = layer.YoloRoot
+	}
+	if layer.SubmoduleRoot != "" {
+		s.SubmoduleRoot = layer.SubmoduleRoot
+	}
+	if layer.Jobs != 0 {
+		s.Jobs = layer.Jobs
+	}
+	if layer.Timeout != "" {
+		s.Timeout = layer.Timeout
+	}
+	if layer.DefaultBranch != "" {
+		s.DefaultBranch = layer.DefaultBranch
+	}
+	if layer.MergeSym != "" {
+		s.MergeSym = layer.MergeSym
+	}
+	if layer.Output != "" {
+		s.Output = layer.Output
+	}
+	return s
+}
+
+// Layer the settings of a later config file over s. Unlike merge the OS overrides are
+// layered too.
+func (s Settings) Layer(later Settings) Settings {
+	osOverrides := s.OS
+	s = s.merge(later)
+	for goos, over := range later.OS {
+		if osOverrides == nil {
+			osOverrides = make(map[string]Settings, len(later.OS))
+		}
+		osOverrides[goos] = osOverrides[goos].merge(over)
+	}
+	s.OS = osOverrides
+	return s
+}
+
+// check the values that can't be checked by their type. returns the parsed timeout.
+func (s Settings) Validate() (time.Duration, error) {
+	if s.Jobs < 0 {
+		return 0, fmt.Errorf("jobs must be 0 (no limit) or more, got %d", s.Jobs)
+	}
+	if s.Output != "text" && s.Output != "json" {
+		return 0, fmt.Errorf("unknown output format %q. expected text or json", s.Output)
+	}
+	if s.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(s.Timeout)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("bad timeout %q. expected a duration like 2m or 30s", s.Timeout)
+	}
+	return timeout, nil
+}
+
+// fill in the branches repos leave out.
+func ApplyRepoDefaults(repos []GitRepo, s Settings) {
+	for i := range repos {
+		if repos[i].BranchMain == "" {
+			repos[i].BranchMain = s.DefaultBranch
+		}
+		if repos[i].BranchUse == "" {
+			repos[i].BranchUse = repos[i].BranchMain
+		}
+	}
+}
+
+// decode the config file text. Accepts the object form {"settings": {}, "repos": []}
+// and the legacy form, a plain array of repos.
+func Decode(src []byte) (Config, error) {
+	cfg, rawRepos, err := decodeRaw(FormatJSONC, src)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Repos = make([]GitRepo, len(rawRepos))
+	for i, raw := range rawRepos {
+		if err = json.Unmarshal(raw, &cfg.Repos[i]); err != nil {
+			return Config{}, err
+		}
+	}
+	return cfg, nil
+}
+
+// decode the config file text, leaving the repos as json. Repos overriding a repo of an
+// earlier file are decoded onto it so only the fields they set change.
+func decodeRaw(format Format, src []byte) (Config, []json.RawMessage, error) {
+	src, err := toJSON(format, src)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	var raw json.RawMessage
+	dec, err := jsonc.NewDecoder(bufio.NewReader(bytes.NewReader(src)))
+	if err != nil {
+		return Config{}, nil, err
+	}
+	if err = dec.Decode(&raw); err != nil {
+		return Config{}, nil, err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '[' {
+		var repos []json.RawMessage
+		err = json.Unmarshal(raw, &repos)
+		return Config{}, repos, err
+	}
+	var obj struct {
+		Config
+		Repos []json.RawMessage `json:"repos"` // shadows Config.Repos
+	}
+	err = json.Unmarshal(raw, &obj)
+	return obj.Config, obj.Repos, err
+}
+
+// expand environment variables in path. ${VAR:-default} uses default when VAR is unset
+// or empty. Variables that are not set expand to "".
+func ExpandEnv(path string) string {
+	return os.Expand(path, func(name string) string {
+		if i := strings.Index(name, ":-"); i >= 0 {
+			if val := os.Getenv(name[:i]); val != "" {
+				return val
+			}
+			return name[i+2:]
+		}
+		return os.Getenv(name)
+	})
+}
+
+// Get the folder ~ expands to. home is the Home setting, "" for the user's home dir.
+// A ~ in home is the user's home dir.
+func HomeDir(home string) (string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	myHomeDir := usr.HomeDir
+	home = ExpandEnv(home)
+	if home == "" {
+		return myHomeDir, nil
+	}
+	if strings.HasPrefix(home, "~") {
+		home = strings.Replace(home, "~", myHomeDir, 1)
+	}
+	return home, nil
+}
+
+// expand environment variables like ${XDG_CONFIG_HOME} or ${VAR:-default} in path, then
+// "~" to home. home is from HomeDir.
+func ExpandPath(path, home string) string {
+	path = ExpandEnv(path)
+	if !strings.HasPrefix(path, "~") {
+		return path
+	}
+	// replace 1st instance of ~ only.
+	path = strings.Replace(path, "~", home, 1)
+	return path
+}
+
+// replace the home prefix of path with "~" so the config works on other machines.
+func ContractPath(path, home string) string {
+	if home != "" && (path == home || strings.HasPrefix(path, home+string(filepath.Separator))) {
+		return filepath.ToSlash("~" + strings.TrimPrefix(path, home))
+	}
+	return filepath.ToSlash(path)
+}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSettingsForOS(t *testing.T) {
+	got := Settings{}.ForOS("linux")
+	want := Settings{
+		EmacsDir:      "~/.emacs.d",
+		YoloRoot:      "~/.emacs.d/notElpaYolo",
+		SubmoduleRoot: "~/.emacs.d/notElpa",
+		DefaultBranch: "master",
+		MergeSym:      "mine",
+		Output:        "text",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("defaults on linux = %+v, want %+v", got, want)
+	}
+	if got = (Settings{}).ForOS("windows"); got.Home != "~/AppData/Local" {
+		t.Errorf("default windows home = %q, want ~/AppData/Local", got.Home)
+	}
+
+	s := Settings{
+		EmacsDir: "${XDG_CONFIG_HOME:-~/.config}/emacs",
+		OS: map[string]Settings{
+			"windows": {Home: "D:/home", YoloRoot: "D:/yolo"},
+		},
+	}
+	got = s.ForOS("linux")
+	if got.YoloRoot != "${XDG_CONFIG_HOME:-~/.config}/emacs/notElpaYolo" || got.Home != "" {
+		t.Errorf("linux = %+v. wanted roots under emacsDir", got)
+	}
+	got = s.ForOS("windows")
+	want = Settings{
+		Home:          "D:/home",
+		EmacsDir:      "${XDG_CONFIG_HOME:-~/.config}/emacs",
+		YoloRoot:      "D:/yolo",
+		SubmoduleRoot: "${XDG_CONFIG_HOME:-~/.config}/emacs/notElpa",
+		DefaultBranch: "master",
+		MergeSym:      "mine",
+		Output:        "text",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("windows = %+v, want %+v", got, want)
+	}
+
+	// CLI flags win over the config file, unset flags don't.
+	s = Settings{Jobs: 4, Timeout: "1m", DefaultBranch: "main", OS: map[string]Settings{"linux": {Jobs: 8}}}
+	got = s.ForOS("linux", Settings{Timeout: "30s", Output: "json"})
+	if got.Jobs != 8 || got.Timeout != "30s" || got.DefaultBranch != "main" || got.Output != "json" || got.MergeSym != "mine" {
+		t.Errorf("with CLI overrides = %+v", got)
+	}
+}
+
+func TestSettingsValidate(t *testing.T) {
+	tests := []struct {
+		s       Settings
+		want    time.Duration
+		wantErr bool
+	}{
+		{Settings{Output: "text"}, 0, false},
+		{Settings{Output: "json", Jobs: 4, Timeout: "2m"}, 2 * time.Minute, false},
+		{Settings{Output: "yaml"}, 0, true},
+		{Settings{Output: "text", Jobs: -1}, 0, true},
+		{Settings{Output: "text", Timeout: "soon"}, 0, true},
+		{Settings{Output: "text", Timeout: "-1s"}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := tt.s.Validate()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%+v validate() = %v, %v. want %v, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestApplyRepoDefaults(t *testing.T) {
+	repos := []GitRepo{
+		{Name: "a"},
+		{Name: "b", BranchMain: "master"},
+		{Name: "c", BranchMain: "master", BranchUse: "mine"},
+	}
+	ApplyRepoDefaults(repos, Settings{DefaultBranch: "main"})
+	want := [][2]string{{"main", "main"}, {"master", "master"}, {"master", "mine"}}
+	for i, repo := range repos {
+		if repo.BranchMain != want[i][0] || repo.BranchUse != want[i][1] {
+			t.Errorf("%s branches = %s %s, want %v", repo.Name, repo.BranchMain, repo.BranchUse, want[i])
+		}
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("GFH_SET", "/set")
+	t.Setenv("GFH_EMPTY", "")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~/.emacs.d", "~/.emacs.d"},
+		{"$GFH_SET/emacs", "/set/emacs"},
+		{"${GFH_SET}/emacs", "/set/emacs"},
+		{"${GFH_SET:-~/.config}/emacs", "/set/emacs"},
+		{"${GFH_EMPTY:-~/.config}/emacs", "~/.config/emacs"},
+		{"${GFH_UNSET_VAR:-~/.config}/emacs", "~/.config/emacs"},
+		{"${GFH_UNSET_VAR}/emacs", "/emacs"},
+	}
+	for _, tt := range tests {
+		if got := ExpandEnv(tt.path); got != tt.want {
+			t.Errorf("ExpandEnv(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("GFH_SET", "/set")
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~/.emacs.d", "/home/mike/.emacs.d"},
+		{"${GFH_SET:-~/.config}/emacs", "/set/emacs"},
+		{"${GFH_UNSET_VAR:-~/.config}/emacs", "/home/mike/.config/emacs"},
+		{"/opt/x~", "/opt/x~"},
+	}
+	for _, tt := range tests {
+		if got := ExpandPath(tt.path, "/home/mike"); got != tt.want {
+			t.Errorf("ExpandPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestContractPath(t *testing.T) {
+	home := "/home/mike"
+	if got := ContractPath("/home/mike/.emacs.d/notElpaYolo/paredit", home); got != "~/.emacs.d/notElpaYolo/paredit" {
+		t.Fatalf("got: %s. wanted a ~ path", got)
+	}
+	// only a whole path segment matches
+	if got := ContractPath("/home/mikey/x", home); got != "/home/mikey/x" {
+		t.Fatalf("got: %s. wanted the path unchanged", got)
+	}
+}
+
+func TestDecodeConfig(t *testing.T) {
+	legacy := `[ // just the repos
+ {"name": "a", "folder": "~/a", "isYolo": true},
+]`
+	cfg, err := Decode([]byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Repos) != 1 || cfg.Repos[0].Name != "a" || cfg.Settings.YoloRoot != "" {
+		t.Errorf("legacy config = %+v", cfg)
+	}
+
+	object := `{
+  // machine specific paths
+  "settings": {"yoloRoot": "~/yolo",
+               "os": {"windows": {"home": "~/AppData/Local"}}},
+  "repos": [
+    {"name": "a", "folder": "~/a", "isYolo": true},
+    {"name": "b", "folder": "~/b", "isYolo": false},
+  ]
+}`
+	cfg, err = Decode([]byte(object))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Repos) != 2 || cfg.Settings.YoloRoot != "~/yolo" || cfg.Settings.OS["windows"].Home != "~/AppData/Local" {
+		t.Errorf("object config = %+v", cfg)
+	}
+}
@@ -0,0 +1,371 @@
+package gitops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gitFetchHelper/config"
+	"golang.org/x/exp/slices"
+)
+
+// new line character.
+var newLine = "\n"
+
+// run git with args in folder dir. returns the trimmed output.
+func Output(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := Command(ctx, dir, args...)
+	output, err := CombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%v %s %s", cmd.Args, err.Error(), output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// get the hash of a branch, tag, or "HEAD" in git repo folder dir.
+func Hash(ctx context.Context, dir, branchTagOrHead string) (string, error) {
+	cmd := Command(ctx, dir, "rev-parse", branchTagOrHead)
+	hash, err := CombinedOutput(cmd)
+	if err != nil {
+		return "", err
+	}
+	hashStr := strings.Trim(string(hash), newLine)
+	return hashStr, nil
+}
+
+// get uncommitted changes (including untracked files) in the repo folder. Empty string
+// if the work tree is clean.
+func UncommittedChanges(ctx context.Context, dir string) (string, error) {
+	cmd := Command(ctx, dir, "status", "--porcelain")
+	output, err := CombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%v %s %s", cmd.Args, err.Error(), output)
+	}
+	return string(output), nil
+}
+
+// get local commits not on any remote tracking branch. Empty string if every commit
+// has been pushed somewhere.
+func UnpushedCommits(ctx context.Context, dir string) (string, error) {
+	cmd := Command(ctx, dir, "log", "--branches", "--not", "--remotes", "--oneline")
+	output, err := CombinedOutput(cmd)
+	if err != nil {
+		return "", fmt.Errorf("%v %s %s", cmd.Args, err.Error(), output)
+	}
+	return string(output), nil
+}
+
+// get list of remote tracking branches for a remote.
+func TrackingBranches(ctx context.Context, dir, remoteAlias string) ([]string, error) {
+	cmd := Command(ctx, dir, "branch", "-r")
+	output, err := CombinedOutput(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) == 0 { // no branches at all!
+		return make([]string, 0), nil
+	}
+	// output might be something like:
+	//     origin/master
+	//     origin/mine
+	//     upstream/master
+	// split the raw shell output to a list of strings
+	allTrackingBranches := strings.Split(string(output), newLine)
+	// trim white space and * character from branch names
+	for i, br := range allTrackingBranches {
+		allTrackingBranches[i] = strings.Trim(br, "\n *")
+	}
+	// only include branches for THIS remote.
+	remoteTrackingBranches := make([]string, 0, len(allTrackingBranches))
+	remoteAliasSlash := remoteAlias + "/"
+	remoteHEAD := remoteAliasSlash + "HEAD"
+	for i := 0; i < len(allTrackingBranches); i++ {
+		branchName := allTrackingBranches[i]
+		isForThisRemote := strings.HasPrefix(branchName, remoteAliasSlash)
+		if isForThisRemote {
+			if strings.HasPrefix(branchName, remoteHEAD) {
+				continue // not interested in the HEAD entry
+			}
+			remoteTrackingBranches = append(remoteTrackingBranches, branchName)
+		}
+	}
+	return remoteTrackingBranches, nil
+}
+
+// get current checked out branch name of the repo in dir.
+// It may be the configured repo.MainBranch, or custom "mine", or empty "" (detached head)
+func CurrentBranch(ctx context.Context, dir string) (string, error) {
+	cmdBranch := Command(ctx, dir, "branch", "--show-current")
+	branchOut, err := CombinedOutput(cmdBranch)
+	if err != nil {
+		return "", err
+	}
+	branchName := strings.Trim(string(branchOut), newLine)
+	return branchName, nil
+}
+
+// True if the repo in dir has a local version of the branch. (ignore remote tracking branches).
+func HasLocalBranch(ctx context.Context, dir, branchName string) (bool, error) {
+	cmd := Command(ctx, dir, "branch")
+	output, err := CombinedOutput(cmd)
+	if err != nil {
+		return false, err
+	}
+	if len(output) == 0 { // no branches at all!
+		return false, nil
+	}
+	// output might be something like:
+	//     master
+	//     mine
+	// split the raw shell output to a list of strings
+	branches := strings.Split(string(output), newLine)
+	// trim white space and * character from branch names
+	for i, br := range branches {
+		branches[i] = strings.Trim(br, "\n *")
+	}
+	hasBranch := slices.Contains(branches, branchName)
+	return hasBranch, nil
+}
+
+// Returns true if folder path is inside a git repo.
+func IsInRepo(ctx context.Context, path string) bool {
+	// git rev-parse --is-inside-work-tree
+	// "true\n"
+	stdout, err := CombinedOutput(Command(ctx, path, "rev-parse", "--is-inside-work-tree"))
+	if err != nil {
+		// git rev-parse throws a fatal err if not in a git repo.
+		// So just interpret err as not in a repo. (don't propagate the err up the chain)
+		return false
+	}
+	return string(stdout) == "true\n"
+}
+
+// Returns true if folder path is inside a git submodule.
+func IsInSubmodule(ctx context.Context, path string) bool {
+	// git rev-parse --show-superproject-working-tree
+	// len(output) > 0
+	stdout, err := CombinedOutput(Command(ctx, path, "rev-parse", "--show-superproject-working-tree"))
+	if err != nil {
+		// git rev-parse throws a fatal err if not in a git repo.
+		// So just interpret err as not in a git submodule. (don't propagate the err up the chain)
+		return false
+	}
+	return len(stdout) > 0
+}
+
+// get the remotes of the repo in dir from git remote -v. Only Alias and URL are set.
+func Remotes(ctx context.Context, dir string) ([]config.Remote, error) {
+	cmd := Command(ctx, dir, "remote", "-v")
+	output, err := CombinedOutput(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("%v %s %s", cmd.Args, err.Error(), output)
+	}
+	return ParseRemoteVerbose(string(output)), nil
+}
+
+// parse the output of git remote -v. The push URL is ignored, only the fetch URL is kept.
+// output might be something like:
+//
+//	origin	https://github.com/miketz/paredit (fetch)
+//	origin	https://github.com/miketz/paredit (push)
+//	upstream	https://paredit.org/paredit.git (fetch)
+//	upstream	https://paredit.org/paredit.git (push)
+func ParseRemoteVerbose(output string) []config.Remote {
+	remotes := make([]config.Remote, 0, 2)
+	for _, line := range strings.Split(output, newLine) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[2] != "(fetch)" {
+			continue
+		}
+		remotes = append(remotes, config.Remote{Alias: fields[0], URL: fields[1]})
+	}
+	return remotes
+}
+
+// refs of a remote as listed by git ls-remote.
+type LsRemoteRefs struct {
+	// branch the remote's HEAD points to. ie "main". empty if not reported.
+	Head string
+	// hash of each branch by short name. ie "main" => "aa1a9b4..."
+	Branches map[string]string
+}
+
+// list the HEAD and branches of remote (an alias or URL) with git ls-remote.
+func LsRemote(ctx context.Context, dir, remote string) (LsRemoteRefs, error) {
+	// git ls-remote --symref origin HEAD refs/heads/*
+	cmd := Command(ctx, dir, "ls-remote", "--symref", remote, "HEAD", "refs/heads/*")
+	output, err := CombinedOutput(cmd)
+	if err != nil {
+		return LsRemoteRefs{}, fmt.Errorf("%v %s %s", cmd.Args, err.Error(), output)
+	}
+	return ParseLsRemote(string(output)), nil
+}
+
+// parse the output of git ls-remote --symref.
+// output might be something like:
+//
+//	ref: refs/heads/main	HEAD
+//	aa1a9b4dcca65abdefe0adcf535acd1ad99b158b	HEAD
+//	aa1a9b4dcca65abdefe0adcf535acd1ad99b158b	refs/heads/dev
+//	aa1a9b4dcca65abdefe0adcf535acd1ad99b158b	refs/heads/main
+func ParseLsRemote(output string) LsRemoteRefs {
+	refs := LsRemoteRefs{Branches: make(map[string]string)}
+	for _, line := range strings.Split(output, newLine) {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 3 && fields[0] == "ref:" && fields[2] == "HEAD":
+			refs.Head = strings.TrimPrefix(fields[1], "refs/heads/")
+		case len(fields) == 2 && strings.HasPrefix(fields[1], "refs/heads/"):
+			refs.Branches[strings.TrimPrefix(fields[1], "refs/heads/")] = fields[0]
+		}
+	}
+	return refs
+}
+
+// status flags of a ref line in the output of git push --porcelain.
+const (
+	PushFastForward = ' '
+	PushForced      = '+'
+	PushDeleted     = '-'
+	PushNew         = '*'
+	PushRejected    = '!'
+	PushUpToDate    = '='
+)
+
+// status of 1 ref from git push --porcelain.
+type PushRefStatus struct {
+	// one of the Push* flag constants
+	Flag byte
+	// "refs/heads/master:refs/heads/master"
+	Ref string
+	// "[up to date]", "[rejected] (fetch first)", "56ccddc...a843e2e (forced update)", etc
+	Summary string
+}
+
+// parse the ref lines of git push --porcelain output. Other lines ("To <url>", "Done",
+// error/hint messages) are ignored.
+// example ref line (tab separated):
+//
+//	!	refs/heads/master:refs/heads/master	[rejected] (fetch first)
+func ParsePushPorcelain(output string) []PushRefStatus {
+	statuses := make([]PushRefStatus, 0, 2)
+	for _, line := range strings.Split(output, newLine) {
+		parts := strings.Split(line, "\t")
+		if len(parts) < 3 || len(parts[0]) != 1 {
+			continue
+		}
+		switch parts[0][0] {
+		case PushFastForward, PushForced, PushDeleted, PushNew, PushRejected, PushUpToDate:
+			statuses = append(statuses, PushRefStatus{
+				Flag:    parts[0][0],
+				Ref:     parts[1],
+				Summary: parts[2],
+			})
+		}
+	}
+	return statuses
+}
+
+// a submodule as configured in .gitmodules.
+type Submodule struct {
+	Name   string
+	Path   string
+	URL    string
+	Branch string
+}
+
+// read the submodules of the superproject in dir from its .gitmodules file.
+func ReadGitmodules(ctx context.Context, dir string) ([]Submodule, error) {
+	// git config --file .gitmodules --get-regexp ^submodule\.
+	cmd := Command(ctx, dir, "config", "--file", ".gitmodules", "--get-regexp", `^submodule\.`)
+	output, err := CombinedOutput(cmd)
+	if err != nil {
+		if len(output) == 0 {
+			return []Submodule{}, nil // git config exits 1 when nothing matches
+		}
+		return nil, fmt.Errorf("%v %s %s", cmd.Args, err.Error(), output)
+	}
+	return ParseGitmodules(string(output)), nil
+}
+
+// parse the output of git config --get-regexp on a .gitmodules file.
+// output might be something like:
+//
+//	submodule.notElpa/magit.path notElpa/magit
+//	submodule.notElpa/magit.url https://github.com/miketz/magit
+//	submodule.notElpa/magit.branch mine
+//
+// submodule names may contain dots, the last dot separates the variable.
+func ParseGitmodules(output string) []Submodule {
+	subs := make([]Submodule, 0, 32)
+	index := make(map[string]int)
+	for _, line := range strings.Split(output, newLine) {
+		key, value, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+		key = strings.TrimPrefix(key, "submodule.")
+		dot := strings.LastIndex(key, ".")
+		if dot < 0 {
+			continue
+		}
+		name, variable := key[:dot], key[dot+1:]
+		i, seen := index[name]
+		if !seen {
+			i = len(subs)
+			index[name] = i
+			subs = append(subs, Submodule{Name: name})
+		}
+		switch variable {
+		case "path":
+			subs[i].Path = value
+		case "url":
+			subs[i].URL = value
+		case "branch":
+			subs[i].Branch = value
+		}
+	}
+	return subs
+}
+
+// add path to the .gitignore of the repo in dir unless git already ignores it.
+func Ignore(ctx context.Context, dir, path string) error {
+	cmd := Command(ctx, dir, "check-ignore", "-q", path)
+	_, err := CombinedOutput(cmd)
+	if err == nil {
+		return nil // already ignored. ie notElpaYolo/ is in .gitignore
+	}
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		return fmt.Errorf("%v %s", cmd.Args, err.Error())
+	}
+
+	ignoreFile := filepath.Join(dir, ".gitignore")
+	content, err := os.ReadFile(ignoreFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	line := "/" + path + "/\n"
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		line = "\n" + line
+	}
+	f, err := os.OpenFile(ignoreFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644) // #nosec G302
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(line)
+	return err
+}
+
+// remove the "remote" prefix from a remote tracking branch name
+// input:  "origin/km/reshelve-rewrite"
+// output: "km/reshelve-rewrite"
+func RemoveRemoteFromBranchName(remoteBranch string) string {
+	i := strings.Index(remoteBranch, "/")
+	return remoteBranch[i+1:]
+}
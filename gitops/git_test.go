@@ -0,0 +1,305 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitFetchHelper/config"
+)
+
+// the folder ~ expands to in the functional tests.
+var home string
+
+func TestMain(m *testing.M) {
+	// setup code here
+	home, _ = config.HomeDir("") // ~ path handling setup
+
+	exitCode := m.Run()
+
+	// teardown code here
+
+	os.Exit(exitCode)
+}
+
+func expandPath(path string) string {
+	return config.ExpandPath(path, home)
+}
+
+// functional test, not unit test.
+// may only work on my machine with folders setup.
+func TestIsGitRepo(t *testing.T) {
+	ctx := context.Background()
+	// repos, but not submods
+	path1 := expandPath("~/.emacs.d")
+	path2 := expandPath("~/.emacs.d/notElpa")
+	// repo and is submod
+	path3 := expandPath("~/.emacs.d/notElpa/magit")
+	// not a repo
+	path4 := expandPath("~/.vscode")
+
+	want := true
+	got := IsInRepo(ctx, path1)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = true
+	got = IsInRepo(ctx, path2)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = true
+	got = IsInRepo(ctx, path3)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = false
+	got = IsInRepo(ctx, path4)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+}
+
+// functional test, not unit test.
+// may only work on my machine with folders setup.
+func TestIsInGitSubmodule(t *testing.T) {
+	ctx := context.Background()
+	// repos, but not submods
+	path1 := expandPath("~/.emacs.d")
+	path2 := expandPath("~/.emacs.d/notElpa")
+	// repo and is submod
+	path3 := expandPath("~/.emacs.d/notElpa/magit")
+	// not a repo
+	path4 := expandPath("~/.vscode")
+
+	want := false
+	got := IsInSubmodule(ctx, path1)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = false
+	got = IsInSubmodule(ctx, path2)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = true
+	got = IsInSubmodule(ctx, path3)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+
+	want = false
+	got = IsInSubmodule(ctx, path4)
+	if got != want {
+		t.Fatalf("got: %t. wanted %t", got, want)
+	}
+}
+
+// functional test, not unit test.
+// may only work on my machine with folders and git repos setup.
+func TestTrackingBranches(t *testing.T) {
+	ctx := context.Background()
+	want := make([]string, 0, 2)
+	want = append(want, "origin/master")
+	want = append(want, "origin/mine")
+
+	got, err := TrackingBranches(ctx, expandPath("~/.emacs.d/notElpaYolo/nov.el"), "origin")
+	if err != nil {
+		t.Fatalf("err during test: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got: %v. wanted %v", got, want)
+	}
+	if got[0] != want[0] {
+		t.Fatalf("got: %v. wanted %v", got, want)
+	}
+	if got[1] != want[1] {
+		t.Fatalf("got: %v. wanted %v", got, want)
+	}
+}
+
+func TestRemoveRemoteFromBranchName(t *testing.T) {
+	// name with extra slashes in it
+	fullBranchName := "origin/km/reshelve-rewrite"
+	got := RemoveRemoteFromBranchName(fullBranchName)
+	want := "km/reshelve-rewrite"
+	if got != want {
+		t.Fatalf("got: %s. wanted %s", got, want)
+	}
+
+	// common typical name. no extra slashes
+	fullBranchName2 := "origin/master"
+	got = RemoveRemoteFromBranchName(fullBranchName2)
+	want = "master"
+	if got != want {
+		t.Fatalf("got: %s. wanted %s", got, want)
+	}
+
+	// normal master branch. no remote!
+	fullBranchName3 := "master"
+	got = RemoveRemoteFromBranchName(fullBranchName3)
+	want = "master"
+	if got != want {
+		t.Fatalf("got: %s. wanted %s", got, want)
+	}
+}
+
+func BenchmarkMergeAlreadyLatest(b *testing.B) {
+	var hasLatest bool
+	dir := expandPath("~/.emacs.d/notElpaYolo/mor")
+	for i := 0; i < b.N; i++ {
+		cmd := exec.Command("git", "merge", "origin/master") // #nosec G204
+		cmd.Dir = dir
+		stdout, err := cmd.CombinedOutput()
+		if err != nil {
+			b.Fatalf("merged errored out! %v", err)
+		}
+		output := string(stdout)
+		hasLatest = output == "Already up to date.\n"
+	}
+	fmt.Printf("merge hasLatest: %v\n", hasLatest)
+	b.ReportAllocs() // include alloc info in report
+}
+
+func BenchmarkCheckAlreadyLatest(b *testing.B) {
+	ctx := context.Background()
+	var hasLatest bool
+	dir := expandPath("~/.emacs.d/notElpaYolo/mor")
+	for i := 0; i < b.N; i++ {
+		// git rev-parse HEAD
+		hashLocal, err := Hash(ctx, dir, "HEAD")
+		if err != nil {
+			b.Fatalf("GetHash errored out! %v", err)
+		}
+		hashRemote, err := Hash(ctx, dir, "origin/master")
+		if err != nil {
+			b.Fatalf("GetHash errored out! %v", err)
+		}
+		hasLatest = hashLocal == hashRemote
+	}
+	fmt.Printf("check hasLatest: %v\n", hasLatest)
+	b.ReportAllocs() // include alloc info in report
+}
+
+func BenchmarkSubstringOld(b *testing.B) {
+	fullBranchName := "origin/km/reshelve-rewrite"
+	for i := 0; i < b.N; i++ {
+		_removeRemoteFromBranchNameOLD(fullBranchName)
+	}
+	b.ReportAllocs() // include alloc info in report
+}
+
+func BenchmarkSubstringNew(b *testing.B) {
+	fullBranchName := "origin/km/reshelve-rewrite"
+	for i := 0; i < b.N; i++ {
+		_removeRemoteFromBranchNameNEW(fullBranchName)
+	}
+	b.ReportAllocs() // include alloc info in report
+}
+
+// tmp fn to compare substirng techniques.
+func _removeRemoteFromBranchNameOLD(remoteBranch string) string {
+	parts := strings.Split(remoteBranch, "/")
+
+	// we cannot simply use parts[1] becuase the remainder of the name may have
+	// contained slashes "/".
+	branchName := "" // := parts[1]
+	// trim off the "origin" prefix, but also add back the "/" in the remainder of the name
+	for i := 1; i < len(parts); i++ {
+		if i == 1 {
+			branchName += parts[i]
+		} else {
+			branchName += "/" + parts[i]
+		}
+	}
+	return branchName
+}
+
+// tmp fn to compare substirng techniques.
+func _removeRemoteFromBranchNameNEW(remoteBranch string) string {
+	i := strings.Index(remoteBranch, "/")
+	return remoteBranch[i+1:]
+}
+
+func TestParsePushPorcelain(t *testing.T) {
+	output := "To https://github.com/miketz/paredit\n" +
+		"=\trefs/heads/master:refs/heads/master\t[up to date]\n" +
+		"!\trefs/heads/mine:refs/heads/mine\t[rejected] (fetch first)\n" +
+		"Done\n"
+	got := ParsePushPorcelain(output)
+	if len(got) != 2 {
+		t.Fatalf("got: %v. wanted 2 ref statuses", got)
+	}
+	if got[0].Flag != PushUpToDate || got[0].Ref != "refs/heads/master:refs/heads/master" {
+		t.Fatalf("got: %v. wanted up to date master", got[0])
+	}
+	if got[1].Flag != PushRejected || got[1].Summary != "[rejected] (fetch first)" {
+		t.Fatalf("got: %v. wanted rejected mine", got[1])
+	}
+
+	// no ref lines at all. ie a failure before anything was pushed.
+	got = ParsePushPorcelain("error: src refspec master does not match any\n")
+	if len(got) != 0 {
+		t.Fatalf("got: %v. wanted no ref statuses", got)
+	}
+}
+
+func TestParseRemoteVerbose(t *testing.T) {
+	output := "origin\thttps://github.com/miketz/paredit (fetch)\n" +
+		"origin\thttps://github.com/miketz/paredit (push)\n" +
+		"upstream\thttps://paredit.org/paredit.git (fetch)\n" +
+		"upstream\tno_push (push)\n"
+	got := ParseRemoteVerbose(output)
+	if len(got) != 2 {
+		t.Fatalf("got: %v. wanted 2 remotes", got)
+	}
+	if got[1].Alias != "upstream" || got[1].URL != "https://paredit.org/paredit.git" {
+		t.Fatalf("got: %v. wanted the upstream fetch url", got[1])
+	}
+}
+
+func TestParseGitmodules(t *testing.T) {
+	output := "submodule.notElpa/magit.path notElpa/magit\n" +
+		"submodule.notElpa/magit.url https://github.com/miketz/magit\n" +
+		"submodule.notElpa/magit.branch mine\n" +
+		"submodule.nov.el.path notElpa/nov.el\n" +
+		"submodule.nov.el.url https://github.com/miketz/nov.el\n"
+	got := ParseGitmodules(output)
+	if len(got) != 2 {
+		t.Fatalf("got: %v. wanted 2 submodules", got)
+	}
+	want := Submodule{Name: "notElpa/magit", Path: "notElpa/magit", URL: "https://github.com/miketz/magit", Branch: "mine"}
+	if got[0] != want {
+		t.Fatalf("got: %v. wanted %v", got[0], want)
+	}
+	// name with a dot in it
+	if got[1].Name != "nov.el" || got[1].Path != "notElpa/nov.el" || got[1].Branch != "" {
+		t.Fatalf("got: %v. wanted nov.el", got[1])
+	}
+}
+
+func TestParseLsRemote(t *testing.T) {
+	output := "ref: refs/heads/main\tHEAD\n" +
+		"aa1a9b4dcca65abdefe0adcf535acd1ad99b158b\tHEAD\n" +
+		"bb1a9b4dcca65abdefe0adcf535acd1ad99b158b\trefs/heads/dev\n" +
+		"aa1a9b4dcca65abdefe0adcf535acd1ad99b158b\trefs/heads/main\n"
+	want := LsRemoteRefs{
+		Head: "main",
+		Branches: map[string]string{
+			"dev":  "bb1a9b4dcca65abdefe0adcf535acd1ad99b158b",
+			"main": "aa1a9b4dcca65abdefe0adcf535acd1ad99b158b",
+		},
+	}
+	if got := ParseLsRemote(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseLsRemote() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,97 @@
+// Package gitops runs the git commands of gitFetchHelper and parses their output.
+// Folders given to it are already expanded, ~ and environment variables mean nothing
+// here.
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Running of the git commands. Every command goes through CombinedOutput so the jobs
+// and timeout settings apply everywhere.
+
+// guards jobSlots and cmdTimeout. SetLimits may be called while commands run.
+var limitsMut sync.Mutex
+
+// limits the # of commands running at once. nil for no limit. set by SetLimits.
+var jobSlots chan struct{}
+
+// commands running longer than this are killed. 0 for no timeout. set by SetLimits.
+var cmdTimeout time.Duration
+
+// how long to wait for the output of a killed or finished command. A child of git, ie
+// git-remote-https of a fetch, may keep the output open after git is gone.
+var waitDelay = time.Second
+
+// set the max # of commands running at once (0 for no limit) and the timeout of each
+// command (0 for none). The limits are for the whole process so operations running at
+// the same time share them.
+func SetLimits(jobs int, timeout time.Duration) {
+	limitsMut.Lock()
+	defer limitsMut.Unlock()
+	jobSlots = nil
+	if jobs > 0 {
+		jobSlots = make(chan struct{}, jobs)
+	}
+	cmdTimeout = timeout
+}
+
+// build a git command run in folder dir. It's killed if ctx is done first.
+func Command(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...) // #nosec G204
+	cmd.Dir = dir
+	return cmd
+}
+
+// run cmd and return its combined stdout and stderr, like cmd.CombinedOutput. Waits for
+// a free job slot first. The command is killed if it runs longer than the timeout.
+// Output still held open by a child of the command is not waited for.
+func CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	// the limits of this call. SetLimits may replace them while the command runs.
+	limitsMut.Lock()
+	slots, timeout := jobSlots, cmdTimeout
+	limitsMut.Unlock()
+	if slots != nil {
+		slots <- struct{}{}
+		defer func() { <-slots }()
+	}
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = waitDelay
+	}
+	if timeout <= 0 {
+		return ignoreWaitDelay(cmd.CombinedOutput())
+	}
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	killed := make(chan bool, 1)
+	killTimer := time.AfterFunc(timeout, func() {
+		// fails if the command is already done, ie only waiting on the output of a child.
+		killed <- cmd.Process.Kill() == nil
+	})
+	err := cmd.Wait()
+	// the timer went off if it can't be stopped. wait for its kill to know if it timed out.
+	if !killTimer.Stop() && <-killed {
+		return output.Bytes(), fmt.Errorf("killed after the %v timeout", timeout)
+	}
+	return ignoreWaitDelay(output.Bytes(), err)
+}
+
+// the command worked but a child of it still had the output open after waitDelay.
+// The command's own output is complete so that's not an error.
+func ignoreWaitDelay(output []byte, err error) ([]byte, error) {
+	if errors.Is(err, exec.ErrWaitDelay) {
+		return output, nil
+	}
+	return output, err
+}
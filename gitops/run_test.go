@@ -0,0 +1,71 @@
+package gitops
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCombinedOutputTimeout(t *testing.T) {
+	defer SetLimits(0, 0)
+
+	SetLimits(1, 200*time.Millisecond)
+	output, err := CombinedOutput(exec.Command("sh", "-c", "echo hi; echo err 1>&2"))
+	if err != nil || string(output) != "hi\nerr\n" {
+		t.Errorf("quick command = %q, %v", output, err)
+	}
+
+	start := time.Now()
+	_, err = CombinedOutput(exec.Command("sleep", "5"))
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("slow command error = %v, wanted a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("slow command took %v. it wasn't killed", elapsed)
+	}
+}
+
+// a child that outlives the killed command doesn't hold up the timeout. ie
+// git-remote-https of a hung git fetch.
+func TestCombinedOutputTimeoutGrandchild(t *testing.T) {
+	defer SetLimits(0, 0)
+
+	SetLimits(1, 200*time.Millisecond)
+	start := time.Now()
+	_, err := CombinedOutput(exec.Command("sh", "-c", "sleep 3 & sleep 3"))
+	if err == nil || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("slow command error = %v, wanted a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("slow command took %v. it waited for the grandchild", elapsed)
+	}
+
+	// the command worked, only its child is still running.
+	output, err := CombinedOutput(exec.Command("sh", "-c", "echo hi; sleep 3 &"))
+	if err != nil || string(output) != "hi\n" {
+		t.Errorf("command with a child left running = %q, %v", output, err)
+	}
+}
+
+// the job slot is given back to the limits it was taken from.
+func TestCombinedOutputSetLimitsWhileRunning(t *testing.T) {
+	defer SetLimits(0, 0)
+
+	SetLimits(1, 0)
+	done := make(chan error, 1)
+	go func() {
+		_, err := CombinedOutput(exec.Command("sleep", "0.2"))
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	SetLimits(0, 0) // no limit. jobSlots is nil while the command above runs
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("command = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("command didn't give back its job slot")
+	}
+}
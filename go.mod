@@ -3,7 +3,10 @@ module gitFetchHelper
 go 1.24.0
 
 require (
-	github.com/komkom/jsonc v0.0.0-20211024105009-cf68880f5077 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
-	golang.org/x/exp v0.0.0-20240808152545-0cdaa3abc0fa // indirect
+	github.com/BurntSushi/toml v1.6.0
+	github.com/komkom/jsonc v0.0.0-20211024105009-cf68880f5077
+	golang.org/x/exp v0.0.0-20240808152545-0cdaa3abc0fa
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require github.com/pkg/errors v0.9.1 // indirect
@@ -1,273 +1,64 @@
 package main
 
 import (
-	"fmt"
-	"os"
-	"os/exec"
-	"strings"
 	"testing"
-)
-
-func TestMain(m *testing.M) {
-	// setup code here
-	homeDir, _ = getHomeDir() // ~ path handling setup
-
-	exitCode := m.Run()
-
-	// teardown code here
-
-	os.Exit(exitCode)
-}
-
-// functional test, not unit test.
-// may only work on my machine with folders setup.
-func TestIsGitRepo(t *testing.T) {
-	// repos, but not submods
-	path1 := expandPath("~/.emacs.d")
-	path2 := expandPath("~/.emacs.d/notElpa")
-	// repo and is submod
-	path3 := expandPath("~/.emacs.d/notElpa/magit")
-	// not a repo
-	path4 := expandPath("~/.vscode")
-
-	want := true
-	got := isInGitRepo(path1)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = true
-	got = isInGitRepo(path2)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = true
-	got = isInGitRepo(path3)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = false
-	got = isInGitRepo(path4)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-}
-
-// functional test, not unit test.
-// may only work on my machine with folders setup.
-func TestIsInGitSubmodule(t *testing.T) {
-	// repos, but not submods
-	path1 := expandPath("~/.emacs.d")
-	path2 := expandPath("~/.emacs.d/notElpa")
-	// repo and is submod
-	path3 := expandPath("~/.emacs.d/notElpa/magit")
-	// not a repo
-	path4 := expandPath("~/.vscode")
-
-	want := false
-	got := isInGitSubmodule(path1)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = false
-	got = isInGitSubmodule(path2)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = true
-	got = isInGitSubmodule(path3)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = false
-	got = isInGitSubmodule(path4)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-}
-
-// functional test, not unit test.
-// may only work on my machine with folders setup.
-func TestExists(t *testing.T) {
-	// repos, but not submods
-	path1 := expandPath("~/.emacs.d")
-	path2 := expandPath("~/.emacs.d/notElpa")
-	// repo and is submod
-	// path3 := expandPath("~/.emacs.d/notElpa/magit")
-	// not a repo
-	path4 := expandPath("~/.vscode")
-	// is a "yolo" repo
-	path5 := expandPath("~/.emacs.d/notElpaYolo/binky.el")
-
-	want := true
-	got, _ := exists(path1)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = true
-	got, _ = exists(path2)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	// want = true
-	// got, _ = exists(path3)
-	// if got != want {
-	// 	t.Fatalf("got: %t. wanted %t", got, want)
-	// }
-
-	want = true
-	got, _ = exists(path4)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = true
-	got, _ = exists(path5)
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-
-	want = false
-	got, _ = exists("~/fake/path")
-	if got != want {
-		t.Fatalf("got: %t. wanted %t", got, want)
-	}
-}
-
-func TestParentDir(t *testing.T) {
-	want := expandPath("~/.emacs.d/notElpaYolo")
-	got := parentDir("~/.emacs.d/notElpaYolo/binky.el")
-	if got != want {
-		t.Fatalf("got: %s. wanted %s", got, want)
-	}
-}
 
-// functional test, not unit test.
-// may only work on my machine with folders and git repos setup.
-func TestTrackingBranches(t *testing.T) {
-	want := make([]string, 0, 2)
-	want = append(want, "origin/master")
-	want = append(want, "origin/mine")
-
-	got, err := TrackingBranches(expandPath("~/.emacs.d/notElpaYolo/nov.el"), "origin")
-	if err != nil {
-		t.Fatalf("err during test: %v", err)
-	}
-	if len(got) != len(want) {
-		t.Fatalf("got: %v. wanted %v", got, want)
-	}
-	if got[0] != want[0] {
-		t.Fatalf("got: %v. wanted %v", got, want)
-	}
-	if got[1] != want[1] {
-		t.Fatalf("got: %v. wanted %v", got, want)
-	}
-}
-
-func TestRemoveRemoteFromBranchName(t *testing.T) {
-	// name with extra slashes in it
-	fullBranchName := "origin/km/reshelve-rewrite"
-	got := removeRemoteFromBranchName(fullBranchName)
-	want := "km/reshelve-rewrite"
-	if got != want {
-		t.Fatalf("got: %s. wanted %s", got, want)
-	}
+	"gitFetchHelper/commands"
+	"gitFetchHelper/config"
+)
 
-	// common typical name. no extra slashes
-	fullBranchName2 := "origin/master"
-	got = removeRemoteFromBranchName(fullBranchName2)
-	want = "master"
-	if got != want {
-		t.Fatalf("got: %s. wanted %s", got, want)
+func TestRemoteCommandRouting(t *testing.T) {
+	tests := []struct {
+		command    string
+		op         string
+		remoteType commands.RemoteType
+	}{
+		{"fetchUpstream", "fetch", commands.RemoteUpstream},
+		{"fetchDefault", "fetch", commands.RemoteDefault},
+		{"fetchMine", "fetch", commands.RemoteMine},
+		{"diffUpstream", "diff", commands.RemoteUpstream},
+		{"diffDefault", "diff", commands.RemoteDefault},
+		{"diffMine", "diff", commands.RemoteMine},
+	}
+	for _, tt := range tests {
+		rc, ok := lookupRemoteCommand(tt.command)
+		if !ok {
+			t.Fatalf("command %s not found", tt.command)
+		}
+		if rc.op != tt.op || rc.remoteType != tt.remoteType {
+			t.Fatalf("command %s routes to %s %v. wanted %s %v",
+				tt.command, rc.op, rc.remoteType, tt.op, tt.remoteType)
+		}
 	}
-
-	// normal master branch. no remote!
-	fullBranchName3 := "master"
-	got = removeRemoteFromBranchName(fullBranchName3)
-	want = "master"
-	if got != want {
-		t.Fatalf("got: %s. wanted %s", got, want)
+	// every command in the table is covered above
+	if got := len(remoteCommands()); got != len(tests) {
+		t.Fatalf("got %d remote commands. wanted %d", got, len(tests))
 	}
-}
-
-func BenchmarkMergeAlreadyLatest(b *testing.B) {
-	var hasLatest bool
-	dir := expandPath("~/.emacs.d/notElpaYolo/mor")
-	for i := 0; i < b.N; i++ {
-		cmd := exec.Command("git", "merge", "origin/master") // #nosec G204
-		cmd.Dir = dir
-		stdout, err := cmd.CombinedOutput()
-		if err != nil {
-			b.Fatalf("merged errored out! %v", err)
-		}
-		output := string(stdout)
-		hasLatest = output == "Already up to date.\n"
+	if _, ok := lookupRemoteCommand("fetchBogus"); ok {
+		t.Fatalf("fetchBogus should not be a command")
 	}
-	fmt.Printf("merge hasLatest: %v\n", hasLatest)
-	b.ReportAllocs() // include alloc info in report
 }
 
-func BenchmarkCheckAlreadyLatest(b *testing.B) {
-	var hasLatest bool
-	dir := expandPath("~/.emacs.d/notElpaYolo/mor")
-	for i := 0; i < b.N; i++ {
-		// git rev-parse HEAD
-		hashLocal, err := GetHash(dir, "HEAD")
-		if err != nil {
-			b.Fatalf("GetHash errored out! %v", err)
-		}
-		hashRemote, err := GetHash(dir, "origin/master")
-		if err != nil {
-			b.Fatalf("GetHash errored out! %v", err)
-		}
-		hasLatest = hashLocal == hashRemote
+func TestInactiveSection(t *testing.T) {
+	inactive := []config.GitRepo{{Name: "w", OS: []string{"windows"}}}
+	sec := inactiveSection(inactive)
+	if len(sec.Lines) != 1 || sec.Lines[0] != "w  skipped (inactive on this machine)\n" {
+		t.Errorf("got %q", sec.Lines)
 	}
-	fmt.Printf("check hasLatest: %v\n", hasLatest)
-	b.ReportAllocs() // include alloc info in report
 }
 
-func BenchmarkSubstringOld(b *testing.B) {
-	fullBranchName := "origin/km/reshelve-rewrite"
-	for i := 0; i < b.N; i++ {
-		_removeRemoteFromBranchNameOLD(fullBranchName)
+func TestSessionAllRepos(t *testing.T) {
+	s := &session{
+		repos:    make([]config.GitRepo, 1, 4),
+		inactive: []config.GitRepo{{Name: "w"}},
 	}
-	b.ReportAllocs() // include alloc info in report
-}
-func BenchmarkSubstringNew(b *testing.B) {
-	fullBranchName := "origin/km/reshelve-rewrite"
-	for i := 0; i < b.N; i++ {
-		_removeRemoteFromBranchNameNEW(fullBranchName)
+	s.repos[0].Name = "a"
+	all := s.allRepos()
+	if len(all) != 2 || all[0].Name != "a" || all[1].Name != "w" {
+		t.Fatalf("got %v. wanted the active then the inactive repos", all)
 	}
-	b.ReportAllocs() // include alloc info in report
-}
-
-// tmp fn to compare substirng techniques.
-func _removeRemoteFromBranchNameOLD(remoteBranch string) string {
-	parts := strings.Split(remoteBranch, "/")
-
-	// we cannot simply use parts[1] becuase the remainder of the name may have
-	// contained slashes "/".
-	branchName := "" // := parts[1]
-	// trim off the "origin" prefix, but also add back the "/" in the remainder of the name
-	for i := 1; i < len(parts); i++ {
-		if i == 1 {
-			branchName += parts[i]
-		} else {
-			branchName += "/" + parts[i]
-		}
+	// the spare capacity of repos is not written to.
+	if s.repos[:2][1].Name != "" {
+		t.Errorf("allRepos() appended to s.repos")
 	}
-	return branchName
-}
-
-// tmp fn to compare substirng techniques.
-func _removeRemoteFromBranchNameNEW(remoteBranch string) string {
-	i := strings.Index(remoteBranch, "/")
-	return remoteBranch[i+1:]
 }
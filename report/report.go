@@ -0,0 +1,77 @@
+// Package report is the output of the gitFetchHelper commands: a summary then titled
+// sections of lines, written as text or json.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Report is the result of a command over the repos.
+type Report struct {
+	// may be several lines. ie the time elapsed then the # of skipped repos.
+	Summary  string
+	Sections []Section
+}
+
+// a titled list of report lines. ie the failures.
+type Section struct {
+	Title string
+	// each line ends with a new line. a repo's line may span several lines.
+	Lines []string
+}
+
+// a section as written in json output.
+type sectionJSON struct {
+	Title string   `json:"title"`
+	Count int      `json:"count"`
+	Lines []string `json:"lines"`
+}
+
+// get the section titled title. nil if there is none.
+func (r *Report) Section(title string) *Section {
+	for i := range r.Sections {
+		if r.Sections[i].Title == title {
+			return &r.Sections[i]
+		}
+	}
+	return nil
+}
+
+// write the summary then each section in format, "text" or "json".
+func Write(w io.Writer, format string, r Report) {
+	if format == "json" {
+		writeJSON(w, r)
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", r.Summary)
+	for _, sec := range r.Sections {
+		fmt.Fprintf(w, "\n%s: %d\n", sec.Title, len(sec.Lines))
+		for i := 0; i < len(sec.Lines); i++ {
+			fmt.Fprint(w, sec.Lines[i])
+		}
+	}
+}
+
+func writeJSON(w io.Writer, r Report) {
+	report := struct {
+		Summary  string        `json:"summary"`
+		Sections []sectionJSON `json:"sections"`
+	}{
+		Summary:  r.Summary,
+		Sections: make([]sectionJSON, 0, len(r.Sections)),
+	}
+	for _, sec := range r.Sections {
+		lines := make([]string, 0, len(sec.Lines))
+		for _, line := range sec.Lines {
+			lines = append(lines, strings.TrimRight(line, "\n"))
+		}
+		report.Sections = append(report.Sections, sectionJSON{Title: sec.Title, Count: len(lines), Lines: lines})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report) // strings only, can't fail
+}
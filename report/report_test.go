@@ -0,0 +1,47 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteReport(t *testing.T) {
+	r := Report{
+		Summary: "Fetched 1 of 1 remotes.",
+		Sections: []Section{
+			{"NEW repo data fetched", []string{"1: ~/a [git fetch origin] <data>\n"}},
+			{"FAILURES", []string{}},
+		},
+	}
+	var text bytes.Buffer
+	Write(&text, "text", r)
+	want := "\nFetched 1 of 1 remotes.\n" +
+		"\nNEW repo data fetched: 1\n1: ~/a [git fetch origin] <data>\n" +
+		"\nFAILURES: 0\n"
+	if text.String() != want {
+		t.Errorf("text report = %q, want %q", text.String(), want)
+	}
+
+	var js bytes.Buffer
+	Write(&js, "json", r)
+	var got struct {
+		Summary  string        `json:"summary"`
+		Sections []sectionJSON `json:"sections"`
+	}
+	if err := json.Unmarshal(js.Bytes(), &got); err != nil {
+		t.Fatalf("json report %s: %v", js.String(), err)
+	}
+	if got.Summary != "Fetched 1 of 1 remotes." || len(got.Sections) != 2 {
+		t.Fatalf("json report = %+v", got)
+	}
+	if sec := got.Sections[0]; sec.Count != 1 || sec.Lines[0] != "1: ~/a [git fetch origin] <data>" {
+		t.Errorf("1st section = %+v. wanted the line without the new line", sec)
+	}
+	if sec := got.Sections[1]; sec.Title != "FAILURES" || sec.Count != 0 || sec.Lines == nil {
+		t.Errorf("2nd section = %+v. wanted an empty list of lines", sec)
+	}
+	if sec := r.Section("FAILURES"); sec == nil || len(sec.Lines) != 0 {
+		t.Errorf("Section(FAILURES) = %+v", sec)
+	}
+}